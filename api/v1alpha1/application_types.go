@@ -20,6 +20,7 @@ import (
 	"errors"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -61,6 +62,36 @@ type ApplicationSpec struct {
 
 	// Status defines application status
 	Status []StatusList `json:"status,omitempty"`
+
+	// DeployJob customizes the pod that runs deploy, diff, delete and smoketest actions
+	DeployJob *DeployJobSpec `json:"deployJob,omitempty"`
+}
+
+// DeployJobSpec customizes the pod that runs deploy actions. Empty fields leave the pod defaults in place.
+type DeployJobSpec struct {
+	// ServiceAccountName is the service account the pod runs as
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Resources are the compute resources of the deploy container
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector restricts the nodes the pod is scheduled on
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations allow the pod to be scheduled on tainted nodes
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// ImagePullSecrets are used to pull the deploy image
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Env lists additional environment variables, such as proxy settings, for the deploy container
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// EnvFrom lists additional sources of environment variables for the deploy container
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// SecurityContext replaces the security context of the deploy container
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
 }
 
 type StatusList struct {
@@ -83,8 +114,41 @@ type ParameterDefinitionSpec struct {
 	Description    string         `json:"description,omitempty"`
 	Required       bool           `json:"required,omitempty"`
 	GenerateSecret GenerateSecret `json:"generateSecret,omitempty"`
+
+	// Type is the type of the parameter value. Defaults to string
+	// +kubebuilder:validation:Enum=string;int;bool;enum;duration;quantity;json
+	Type string `json:"type,omitempty"`
+
+	// Enum lists the allowed values
+	Enum []string `json:"enum,omitempty"`
+
+	// Pattern is a regular expression the value must match
+	Pattern string `json:"pattern,omitempty"`
+
+	// Min is the minimum value of an int, duration or quantity parameter
+	Min string `json:"min,omitempty"`
+
+	// Max is the maximum value of an int, duration or quantity parameter
+	Max string `json:"max,omitempty"`
+
+	// Sensitive parameters are delivered to the deploy job as secrets and hidden from output
+	Sensitive bool `json:"sensitive,omitempty"`
+
+	// Template parameters have their default and value evaluated as Go templates. Other values are used as written,
+	// even if they contain "{{"
+	Template bool `json:"template,omitempty"`
 }
 
+const (
+	ParameterTypeString   = "string"
+	ParameterTypeInt      = "int"
+	ParameterTypeBool     = "bool"
+	ParameterTypeEnum     = "enum"
+	ParameterTypeDuration = "duration"
+	ParameterTypeQuantity = "quantity"
+	ParameterTypeJSON     = "json"
+)
+
 type OutputDefinitionSpec struct {
 	Name        string `json:"name,omitempty"`
 	Description string `json:"description,omitempty"`
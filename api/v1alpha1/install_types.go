@@ -17,6 +17,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -52,24 +53,105 @@ type InstallSpec struct {
 
 	// Secrets are a list of installation secrets
 	Secrets []ParameterSpec `json:"secrets,omitempty"`
+
+	// DeployJob overrides the application's deploy job customizations
+	DeployJob *DeployJobSpec `json:"deployJob,omitempty"`
 }
 
 type ParameterSpec struct {
 	Name           string         `json:"name"`
-	Value          string         `json:"value"`
+	Value          string         `json:"value,omitempty"`
 	GenerateSecret GenerateSecret `json:"generateSecret,omitempty"`
+
+	// ValueFrom reads the value from a Secret or ConfigMap in the install's namespace when the install is deployed
+	ValueFrom *ParameterValueSource `json:"valueFrom,omitempty"`
+}
+
+// ParameterValueSource references a key of a Secret or ConfigMap. Exactly one of the fields should be set.
+type ParameterValueSource struct {
+	// SecretKeyRef selects a key of a Secret
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// ConfigMapKeyRef selects a key of a ConfigMap
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
 }
 
 type GenerateSecret struct {
+	// Format is the kind of value to generate: hex, base64, password, uuid, rsa, ecdsa, ed25519 or tls
 	Format string `json:"format" yaml:"format"`
-	Bytes  int    `json:"bytes,omitempty"`
-	Bits   int    `json:"bits,omitempty"`
+
+	// Bytes is the number of random bytes encoded by the hex and base64 formats
+	// +kubebuilder:validation:Minimum=0
+	Bytes int `json:"bytes,omitempty"`
+
+	// Bits is the size of rsa keys
+	Bits int `json:"bits,omitempty"`
+
+	// Length is the length of generated passwords
+	// +kubebuilder:validation:Minimum=0
+	Length int `json:"length,omitempty"`
+
+	// Charsets are the character classes used in generated passwords: lower, upper, digits and symbols. Every class
+	// appears at least once in the password.
+	Charsets []string `json:"charsets,omitempty"`
+
+	// Symbols replaces the characters of the symbols class
+	Symbols string `json:"symbols,omitempty"`
+
+	// Curve is the curve of ecdsa keys: P256, P384 or P521
+	Curve string `json:"curve,omitempty"`
+
+	// CommonName is the subject common name of tls certificates
+	CommonName string `json:"commonName,omitempty"`
+
+	// DNSNames are the DNS subject alternative names of tls certificates
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// IPAddresses are the IP subject alternative names of tls certificates
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+
+	// ValidityDays is the number of days tls certificates are valid for
+	ValidityDays int `json:"validityDays,omitempty"`
 }
 
 // InstallStatus defines the observed state of Install
 type InstallStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// LastApplied records the inputs of the last successful deploy
+	LastApplied *LastAppliedStatus `json:"lastApplied,omitempty"`
+
+	// Drift records the last check for changes made to the install's resources outside of kb
+	Drift *DriftStatus `json:"drift,omitempty"`
+}
+
+type LastAppliedStatus struct {
+	// ManifestGeneration is the generation of the manifest that deployed the install, if any
+	ManifestGeneration int64 `json:"manifestGeneration,omitempty"`
+
+	// Version is the install version that was deployed
+	Version string `json:"version"`
+
+	// InputsHash is a hash of the parameters, install spec and flavor given to the deploy job
+	InputsHash string `json:"inputsHash"`
+
+	// Time is when the deploy completed
+	Time metav1.Time `json:"time"`
+}
+
+type DriftStatus struct {
+	// Drifted is true if the live resources differ from the resources rendered by the bundle
+	Drifted bool `json:"drifted"`
+
+	// Error is why the last check failed, if it did
+	Error string `json:"error,omitempty"`
+
+	// LastChecked is when the diff was last run
+	LastChecked metav1.Time `json:"lastChecked"`
+
+	// LastRemediated is when the install was last re-applied to undo drift
+	LastRemediated *metav1.Time `json:"lastRemediated,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -40,6 +40,10 @@ type ManifestSpec struct {
 	// Bundles is the list of bundles to install
 	Bundles []BundleSpec `json:"bundles"`
 
+	// Parameters are set on every bundle whose application defines a parameter of the same name. Parameters set on a
+	// bundle take precedence.
+	Parameters []ParameterSpec `json:"parameters,omitempty"`
+
 	// CPU is the required cluster CPU
 	CPU string `json:"cpu,omitempty"`
 
@@ -52,6 +56,19 @@ type BundleSpec struct {
 	Version    string          `json:"version"`
 	Parameters []ParameterSpec `json:"parameters,omitempty"`
 	Requires   []RequiresList  `json:"requires,omitempty"`
+
+	// Timeout overrides the deploy timeout for this bundle
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retries is the number of times a failed deploy is retried
+	// +kubebuilder:validation:Minimum=0
+	Retries int `json:"retries,omitempty"`
+
+	// RetryBackoff is the delay before the first retry. The delay doubles for each further retry.
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+
+	// AllowFailure lets the manifest deploy succeed when this bundle fails. Bundles that require it are skipped.
+	AllowFailure bool `json:"allowFailure,omitempty"`
 }
 
 type SourceInfo struct {
@@ -63,9 +63,14 @@ type Registry struct {
 	Status RegistryStatus `json:"status,omitempty"`
 }
 
+// ClusterUrl returns the URL of the registry through the cluster's registry proxy. The namespace of a registry outside
+// the default namespace is part of the path, so that the proxy shared by all namespaces can find the registry's service.
 func (r *Registry) ClusterUrl() string {
 	regName := r.SanitizedRegistryName()
 	registryUrl := fmt.Sprintf("localhost:6000/registry-%s", regName)
+	if r.Namespace != "" && r.Namespace != "default" {
+		registryUrl += "." + r.Namespace
+	}
 	return registryUrl
 }
 
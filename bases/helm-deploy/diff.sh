@@ -4,8 +4,17 @@ set -euxo pipefail
 # fill in overrides.yaml.tmpl to create overrides.yaml
 "$DIR/interpolate.sh"
 
+# Print the diff between the kb-diff markers. helm diff exits 2 when changes are present.
+set +ex
+echo "kb-diff: begin"
 helm diff upgrade \
+  --detailed-exitcode \
   -n="${K8S_NAMESPACE}" \
   --values "$DIR/overrides.yaml" \
   "${K8S_RELEASE_NAME}${K8S_RESOURCE_SUFFIX}" \
   "./helm-charts/$K8S_RELEASE_NAME"
+case $? in
+  0) echo "kb-diff: unchanged" ;;
+  2) echo "kb-diff: changed" ;;
+  *) exit 1 ;;
+esac
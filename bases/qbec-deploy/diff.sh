@@ -7,5 +7,13 @@ qbec_env=$(jq -r .env < $CONFIG_JSON)
 $DIR/generate_kubeconfig.sh
 export KUBECONFIG=/kubeconfig
 
-# Print the diff
-qbec diff $qbec_env --root $DIR
+# Print the diff between the kb-diff markers. With --error-exit=false qbec diff only fails on errors, so changes are
+# found by the file headers of the diff.
+set +x
+echo "kb-diff: begin"
+qbec diff $qbec_env --root $DIR --error-exit=false | tee /tmp/diff.txt
+if grep -q '^+++ ' /tmp/diff.txt; then
+  echo "kb-diff: changed"
+else
+  echo "kb-diff: unchanged"
+fi
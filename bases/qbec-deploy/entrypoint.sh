@@ -8,6 +8,14 @@ export INSTALL_JSON=/config/install.json
 export REQUIRES_JSON=/config/requires.json
 export FLAVOR_JSON=/config/flavor.json
 export INPUT_DIR=/config/inputs
+export SECRETS_JSON=/config/secrets.json
+export OUTPUTS_JSON=/tmp/outputs.json
+
+# Secret parameters are delivered separately from parameters.json. Merge them so every parameter can be read from CONFIG_JSON
+if [ -f "$SECRETS_JSON" ]; then
+  jq -s '.[0] * .[1]' /config/parameters.json "$SECRETS_JSON" > /tmp/parameters.json
+  export CONFIG_JSON=/tmp/parameters.json
+fi
 
 sub_help() {
   echo "Usage: $PROG <subcommand> [options]"
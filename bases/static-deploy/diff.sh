@@ -4,5 +4,12 @@ set -euxo pipefail
 k8s_namespace=$(jq -r .namespace < $CONFIG_JSON)
 $DIR/interpolate.sh
 
-# Print the diff. Ignore any failures since diff returns nonzero when any changes are present.
-kubectl diff -f $DIR/manifests/ -n $k8s_namespace || true
+# Print the diff between the kb-diff markers. kubectl diff exits 1 when changes are present.
+set +ex
+echo "kb-diff: begin"
+kubectl diff -f $DIR/manifests/ -n $k8s_namespace
+case $? in
+  0) echo "kb-diff: unchanged" ;;
+  1) echo "kb-diff: changed" ;;
+  *) exit 1 ;;
+esac
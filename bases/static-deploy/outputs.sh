@@ -1,4 +1,19 @@
 #!/bin/bash
 set -euo pipefail
 
-# TODO: update configmap with any outputs
+# Outputs are a JSON object written to $OUTPUTS_JSON, either by apply.sh or by the bundle's outputs-hook.sh, which
+# prints them. They're saved to the install's configmap as outputs.json, where installs that require this one read them.
+if [ -x "$DIR/outputs-hook.sh" ]; then
+  "$DIR/outputs-hook.sh" > "$OUTPUTS_JSON"
+fi
+if [ ! -f "$OUTPUTS_JSON" ]; then
+  echo '{}' > "$OUTPUTS_JSON"
+fi
+
+if ! jq -e 'type == "object"' < "$OUTPUTS_JSON" > /dev/null; then
+  echo "Outputs in $OUTPUTS_JSON must be a JSON object" >&2
+  exit 1
+fi
+
+patch=$(jq -n --arg outputs "$(jq -c . < "$OUTPUTS_JSON")" '{data: {"outputs.json": $outputs}}')
+kubectl patch configmap "$INSTALL_CONFIGMAP" -n "$INSTALL_NAMESPACE" --type merge -p "$patch"
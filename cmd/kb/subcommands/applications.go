@@ -19,7 +19,9 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/pkg/errors"
@@ -38,6 +40,9 @@ var (
 )
 
 func init() {
+	addOutputFlag(getApplicationsCmd)
+	addOutputFlag(descApplicationsCmd)
+
 	getCmd.AddCommand(getApplicationsCmd)
 	describeCmd.AddCommand(descApplicationsCmd)
 	createCmd.AddCommand(createApplicationCmd)
@@ -55,24 +60,53 @@ var getApplicationsCmd = &cobra.Command{
 }
 
 func listApplications(args []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	mgr := managers.NewResourceManager(c)
 	var list v1alpha1.ApplicationList
-	err := mgr.List(ctx, defaultNamespace, &list)
+	err = mgr.List(ctx, namespace, &list)
 	if err != nil {
 		return errors.Wrap(err, "couldn't list applications")
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-	fmt.Fprintf(w, "APPLICATION\tNAME\tVERSION\t\n")
-
+	names := make([]string, 0, len(list.Items))
 	for _, app := range list.Items {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", app.Name, app.Spec.Name, app.Spec.Version)
+		names = append(names, app.Name)
 	}
 
-	return w.Flush()
+	return p.Print(os.Stdout, &list, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		if wide {
+			fmt.Fprintf(w, "APPLICATION\tNAME\tVERSION\tDEPLOY IMAGE\tPROVIDES\tREQUIRES\n")
+		} else {
+			fmt.Fprintf(w, "APPLICATION\tNAME\tVERSION\t\n")
+		}
+
+		for _, app := range list.Items {
+			if !wide {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", app.Name, app.Spec.Name, app.Spec.Version)
+				continue
+			}
+
+			var provides, requires []string
+			for _, p := range app.Spec.Provides {
+				provides = append(provides, p.Name)
+			}
+			for _, r := range app.Spec.Requires {
+				requires = append(requires, r.Name)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", app.Name, app.Spec.Name, app.Spec.Version, app.Spec.DeployImage,
+				strings.Join(provides, ","), strings.Join(requires, ","))
+		}
+
+		return w.Flush()
+	})
 }
 
 var descApplicationsCmd = &cobra.Command{
@@ -80,49 +114,73 @@ var descApplicationsCmd = &cobra.Command{
 	Aliases: useApplicationAliases,
 	Short:   "Describe Applications",
 	Long:    "Describe Applications",
-	Args:    cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return descApplications(args)
 	},
 }
 
 func descApplications(applications []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	mgr := managers.NewResourceManager(c)
 	var list v1alpha1.ApplicationList
 	if len(applications) == 0 {
-		err := mgr.List(ctx, defaultNamespace, &list)
+		err := mgr.List(ctx, namespace, &list)
 		if err != nil {
 			return errors.Wrap(err, "couldn't list applications")
 		}
 	} else {
-		var app v1alpha1.Application
-		err := mgr.Get(ctx, applications[0], defaultNamespace, &app)
-		if err != nil {
-			return errors.Wrap(err, "couldn't get applications")
+		for _, appName := range applications {
+			var app v1alpha1.Application
+			err := mgr.Get(ctx, appName, namespace, &app)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't get application %q", appName)
+			}
+			list.Items = append(list.Items, app)
 		}
-		list.Items = append(list.Items, app)
 	}
 
+	names := make([]string, 0, len(list.Items))
 	for _, app := range list.Items {
-		fmt.Printf("Application Name: %s\n", app.Name)
-		fmt.Printf("Version: %s\n", app.Spec.Version)
-		fmt.Printf("\nParameter Definitions\n==========\n")
-
-		w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-		fmt.Fprintf(w, "NAME\tDEFAULT\tDESCRIPTION\n")
-
-		for _, parameterDesc := range app.Spec.ParameterDefinitions {
-			fmt.Fprintf(w, "%s\t%s\t%s\n", parameterDesc.Name, parameterDesc.Default, parameterDesc.Description)
-		}
-
-		w.Flush()
-		fmt.Println()
+		names = append(names, app.Name)
 	}
 
-	return nil
+	return p.Print(os.Stdout, list.Items, names, func(out io.Writer, wide bool) error {
+		for _, app := range list.Items {
+			fmt.Fprintf(out, "Application Name: %s\n", app.Name)
+			fmt.Fprintf(out, "Version: %s\n", app.Spec.Version)
+			fmt.Fprintf(out, "\nParameter Definitions\n==========\n")
+
+			w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+			if wide {
+				fmt.Fprintf(w, "NAME\tTYPE\tREQUIRED\tSENSITIVE\tDEFAULT\tDESCRIPTION\n")
+			} else {
+				fmt.Fprintf(w, "NAME\tDEFAULT\tDESCRIPTION\n")
+			}
+
+			for _, parameterDesc := range app.Spec.ParameterDefinitions {
+				if wide {
+					fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\n", parameterDesc.Name, parameterDesc.Type, parameterDesc.Required, parameterDesc.Sensitive,
+						parameterDesc.Default, parameterDesc.Description)
+				} else {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", parameterDesc.Name, parameterDesc.Default, parameterDesc.Description)
+				}
+			}
+
+			err := w.Flush()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out)
+		}
+		return nil
+	})
 }
 
 var createApplicationCmd = &cobra.Command{
@@ -154,7 +212,7 @@ func deleteApplications(applications []string) error {
 
 	for _, appName := range applications {
 		var app v1alpha1.Application
-		err := mgr.Delete(ctx, appName, defaultNamespace, &app)
+		err := mgr.Delete(ctx, appName, namespace, &app)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't delete application %q", appName)
 		}
@@ -68,7 +68,7 @@ func runBootstrap() error {
 		RegistryURL: registryURL,
 		ProjectID:   projectID,
 	}
-	err := mgr.DeployAll(ctx, skipCRDs, skipBindings, skipFlavors, skipAirgap, provider, info)
+	err := mgr.DeployAll(ctx, namespace, skipCRDs, skipBindings, skipFlavors, skipAirgap, provider, info)
 	if err != nil {
 		return errors.Wrap(err, "couldn't run bootstrap")
 	}
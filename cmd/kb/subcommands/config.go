@@ -19,19 +19,47 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/splunk/kube-bundler/managers"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	outputFile        string
+	includeSecretRefs bool
+	dryRun            bool
+	applyConfig       bool
+	withDependents    bool
 )
 
 func init() {
+	exportConfigCmd.Flags().StringVarP(&outputFile, "output-file", "f", "", "write the configuration to a file instead of stdout")
+	exportConfigCmd.Flags().BoolVarP(&includeSecretRefs, "include-secret-refs", "", false, "include parameters read from secrets")
+
+	setConfigCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "only validate the values and show the changes")
+	setConfigCmd.Flags().BoolVarP(&applyConfig, "apply", "", false, "deploy the install after setting the values")
+	setConfigCmd.Flags().BoolVarP(&withDependents, "with-dependents", "", false, "with --apply, also deploy the installs requiring the install whose inputs changed")
+	setConfigCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy logs")
+	setConfigCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+
+	importConfigCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "only show the changes")
+
+	addOutputFlag(listConfigCmd)
+
 	configCmd.AddCommand(getConfigCmd)
 	configCmd.AddCommand(listConfigCmd)
 	configCmd.AddCommand(setConfigCmd)
 	configCmd.AddCommand(removeConfigCmd)
+	configCmd.AddCommand(exportConfigCmd)
+	configCmd.AddCommand(importConfigCmd)
 
 	rootCmd.AddCommand(configCmd)
 }
@@ -62,7 +90,7 @@ func getConfig(installName, key string) error {
 	configMgr := managers.NewConfigManager(c)
 	installRef := managers.InstallReference{
 		Name:      installName,
-		Namespace: defaultNamespace,
+		Namespace: namespace,
 	}
 
 	value, err := configMgr.Get(ctx, installRef, key)
@@ -85,13 +113,18 @@ var listConfigCmd = &cobra.Command{
 }
 
 func listConfig(installName string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	configMgr := managers.NewConfigManager(c)
 	installRef := managers.InstallReference{
 		Name:      installName,
-		Namespace: defaultNamespace,
+		Namespace: namespace,
 	}
 
 	values, err := configMgr.List(ctx, installRef)
@@ -105,16 +138,19 @@ func listConfig(installName string) error {
 	}
 	sort.Strings(sortedKeys)
 
-	for _, key := range sortedKeys {
-		fmt.Printf("%s=%s\n", key, values[key])
-	}
-	return nil
+	// Config values have no more columns to show with -o wide
+	return p.Print(os.Stdout, values, sortedKeys, func(out io.Writer, wide bool) error {
+		for _, key := range sortedKeys {
+			fmt.Fprintf(out, "%s=%s\n", key, values[key])
+		}
+		return nil
+	})
 }
 
 var setConfigCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Set a config value",
-	Long:  "Set a config value",
+	Long:  "Set config values. The values are validated and the changes are shown. The install isn't redeployed unless --apply is given.",
 	Args:  cobra.MinimumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return setConfig(args[0], args[1:])
@@ -128,21 +164,44 @@ func setConfig(installName string, keyValuePairs []string) error {
 	configMgr := managers.NewConfigManager(c)
 	installRef := managers.InstallReference{
 		Name:      installName,
-		Namespace: defaultNamespace,
+		Namespace: namespace,
 	}
 
+	values := make(map[string]string)
 	for _, keyValue := range keyValuePairs {
 		key, value, err := splitKeyValue(keyValue)
 		if err != nil {
 			return errors.Wrap(err, "couldn't split key/value")
 		}
+		values[key] = value
+	}
 
-		err = configMgr.Set(ctx, installRef, key, value)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't set config value '%s'", key)
+	changes, err := configMgr.SetValues(ctx, installRef, values, !dryRun)
+	if err != nil {
+		return errors.Wrap(err, "couldn't set config values")
+	}
+	printConfigChanges(changes)
+	if dryRun {
+		return nil
+	}
+
+	if !applyConfig {
+		if len(changes) > 0 {
+			fmt.Printf("Run 'kb deploy bundle %s' or set the values with --apply to deploy the changes\n", installName)
 		}
+		return nil
 	}
 
+	opts := managers.ConfigApplyOpts{
+		WithDependents: withDependents,
+		Timeout:        time.Duration(timeoutSeconds) * time.Second,
+		ShowLogs:       showLogs,
+	}
+	deployed, err := configMgr.Apply(ctx, installRef, opts)
+	if err != nil {
+		return errors.Wrap(err, "couldn't apply config values")
+	}
+	fmt.Printf("Deployed %s\n", strings.Join(deployed, ", "))
 	return nil
 }
 
@@ -163,7 +222,7 @@ func removeConfig(installName string, keys []string) error {
 	configMgr := managers.NewConfigManager(c)
 	installRef := managers.InstallReference{
 		Name:      installName,
-		Namespace: defaultNamespace,
+		Namespace: namespace,
 	}
 
 	for _, key := range keys {
@@ -187,3 +246,110 @@ func splitKeyValue(keyValue string) (string, string, error) {
 		return splits[0], value, nil
 	}
 }
+
+var exportConfigCmd = &cobra.Command{
+	Use:   "export [install...]",
+	Short: "Export config values",
+	Long:  "Export the non-default config values of all installs, or of the given installs, as yaml",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportConfig(args)
+	},
+}
+
+func exportConfig(installs []string) error {
+	c := setup()
+
+	ctx := context.Background()
+	configMgr := managers.NewConfigManager(c)
+
+	export, err := configMgr.Export(ctx, namespace, installs, includeSecretRefs)
+	if err != nil {
+		return errors.Wrap(err, "couldn't export config values")
+	}
+
+	b, err := yaml.Marshal(export)
+	if err != nil {
+		return errors.Wrap(err, "couldn't encode config values")
+	}
+
+	if outputFile == "" {
+		fmt.Print(string(b))
+		return nil
+	}
+	err = os.WriteFile(outputFile, b, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write %q", outputFile)
+	}
+	return nil
+}
+
+var importConfigCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import config values",
+	Long:  "Import config values exported by kb config export. The values are validated and the changes are shown before they're applied.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return importConfig(args[0])
+	},
+}
+
+func importConfig(filename string) error {
+	c := setup()
+
+	ctx := context.Background()
+	configMgr := managers.NewConfigManager(c)
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't open %q", filename)
+	}
+	defer f.Close()
+
+	var export managers.ConfigExport
+	decoder := k8syaml.NewYAMLOrJSONDecoder(f, 100)
+	err = decoder.Decode(&export)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't decode %q", filename)
+	}
+
+	// Validate and show the changes before applying them
+	changes, err := configMgr.Import(ctx, namespace, export, false)
+	if err != nil {
+		return errors.Wrap(err, "couldn't import config values")
+	}
+	printConfigChanges(changes)
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+
+	_, err = configMgr.Import(ctx, namespace, export, true)
+	if err != nil {
+		return errors.Wrap(err, "couldn't import config values")
+	}
+	fmt.Printf("Applied %d changes\n", len(changes))
+	return nil
+}
+
+// printConfigChanges prints the changes of config values, grouped by install
+func printConfigChanges(changes []managers.ConfigChange) {
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	install := ""
+	for _, change := range changes {
+		if change.Install != install {
+			install = change.Install
+			fmt.Printf("%s:\n", install)
+		}
+		fmt.Printf("  %s: %s -> %s\n", change.Parameter, displayValue(change.Before), displayValue(change.After))
+	}
+}
+
+func displayValue(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package subcommands
 
 const (
-	// TODO: parameterize this
 	defaultNamespace = "default"
 	defaultFlavor    = "default"
 )
@@ -36,6 +36,7 @@ func init() {
 	copyManifestCmd.Flags().StringVarP(&destinationSourceFilename, "destination", "d", "", "source to copy the bundles to")
 	copyManifestCmd.Flags().StringVarP(&section, "section", "", "latest", "section prefix used for copy")
 	copyManifestCmd.Flags().StringVarP(&release, "release", "", "main", "release prefix used for copy")
+	copyManifestCmd.Flags().StringVarP(&lockFilename, "lockfile", "", "", "copy the bundle versions and digests in a lockfile written by kb manifest lock")
 
 	copyBundleCmd.Flags().StringVarP(&configFilename, "config", "c", "", "configuration source used by bundle copy")
 	copyBundleCmd.Flags().StringVarP(&destinationSourceFilename, "destination", "d", "", "source to copy the bundles to")
@@ -68,7 +69,7 @@ var copyBundleCmd = &cobra.Command{
 		for _, bundle := range args {
 			bundleRefs = append(bundleRefs, managers.BundleRef{Name: bundle, Version: managers.Latest})
 		}
-		return copyBundles(configFilename, destinationSourceFilename, bundleRefs)
+		return copyBundles(configFilename, destinationSourceFilename, bundleRefs, nil)
 	},
 }
 
@@ -81,7 +82,9 @@ var copyManifestCmd = &cobra.Command{
 	},
 }
 
-func copyBundles(configFilename, destinationSourceFileName string, bundleRefs []managers.BundleRef) error {
+// copyBundles copies bundles between the sources in two files. If lock is set, only the locked versions and contents
+// are copied.
+func copyBundles(configFilename, destinationSourceFileName string, bundleRefs []managers.BundleRef, lock *managers.ManifestLock) error {
 	ctx := context.Background()
 	copyMgr := managers.NewCopyManager()
 
@@ -115,12 +118,15 @@ func copyBundles(configFilename, destinationSourceFileName string, bundleRefs []
 	if err != nil {
 		return errors.Wrapf(err, "couldn't create source instance '%s'", fromSourceConfig.Name)
 	}
+	if lock != nil {
+		fromSource = managers.NewLockedSource(fromSource, lock)
+	}
 
 	destinationSource, err := managers.NewSource(destinationSourceConfig.Spec.Type, destinationSourceConfig.Spec.Path, destinationSourceConfig.Spec.Options, section, release)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't create destination instance '%s'", destinationSourceConfig.Name)
 	}
-	err = copyMgr.Copy(ctx, fromSource, destinationSource, defaultNamespace, bundleRefs)
+	err = copyMgr.Copy(ctx, fromSource, destinationSource, namespace, bundleRefs)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't copy bundle")
 	}
@@ -128,28 +134,20 @@ func copyBundles(configFilename, destinationSourceFileName string, bundleRefs []
 }
 
 func copyManifest(configFilename, destinationSourceFilename, manifestFilename string) error {
-
-	f, err := os.Open(manifestFilename)
+	manifest, err := managers.LoadManifestFile(manifestFilename)
 	if err != nil {
-		return errors.Wrap(err, "couldn't open manifest file")
+		return err
 	}
-	defer f.Close()
 
-	var manifest v1alpha1.Manifest
-	decoder := yaml.NewYAMLOrJSONDecoder(f, 100)
-	err = decoder.Decode(&manifest)
+	lock, err := loadLock(lockFilename)
 	if err != nil {
-		return errors.Wrap(err, "couldn't decode manifest yaml")
+		return err
 	}
 
-	bundleRefs := make([]managers.BundleRef, 0)
+	var bundleRefs []managers.BundleRef
 	for _, bundle := range manifest.Spec.Bundles {
 		bundleRefs = append(bundleRefs, managers.BundleRef{Name: bundle.Name, Version: bundle.Version})
 	}
 
-	err = copyBundles(configFilename, destinationSourceFilename, bundleRefs)
-	if err != nil {
-		return errors.Wrap(err, "couldn't copy bundles in the manifest file")
-	}
-	return nil
+	return copyBundles(configFilename, destinationSourceFilename, bundleRefs, lock)
 }
@@ -18,20 +18,35 @@ package subcommands
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
 	"github.com/splunk/kube-bundler/managers"
 )
 
 var (
 	controllerOnly bool
+
+	deployContexts     []string
+	clusterParallelism int
 )
 
 func init() {
 	deployManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy and smoketest logs")
 	deployManifestCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+	deployManifestCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "maximum number of installs to deploy at once within a dependency layer")
+	deployManifestCmd.Flags().BoolVarP(&continueOnError, "continue-on-error", "", false, "keep deploying installs that don't depend on a failed install")
+	deployManifestCmd.Flags().BoolVarP(&resume, "resume", "", false, "skip installs whose inputs haven't changed since their last successful deploy")
+	deployManifestCmd.Flags().StringSliceVarP(&deployContexts, "contexts", "", nil, "kubeconfig contexts of the clusters to deploy to")
+	deployManifestCmd.Flags().IntVarP(&clusterParallelism, "cluster-parallelism", "", 4, "with --contexts, maximum number of clusters to deploy to at once")
+	addSelectionFlags(deployManifestCmd)
+	addOverlayFlag(deployManifestCmd)
 
 	deployBundleCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
 	deployBundleCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy logs")
@@ -62,6 +77,18 @@ var deployManifestCmd = &cobra.Command{
 }
 
 func deployManifest(manifests []string) error {
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
+	if len(deployContexts) > 0 {
+		if kubeContext != "" {
+			return errors.New("only one of --context and --contexts may be set")
+		}
+		return deployManifestContexts(manifests, overlays, deployContexts)
+	}
+
 	c := setup()
 
 	ctx := context.Background()
@@ -70,10 +97,13 @@ func deployManifest(manifests []string) error {
 	for _, manifest := range manifests {
 		manifestRef := managers.ManifestReference{
 			Name:      manifest,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
+			Overlays:  overlays,
+			Selection: installSelection(),
 		}
 
-		err := manifestMgr.DeploySmoketest(ctx, manifestRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
+		results, err := manifestMgr.DeploySmoketest(ctx, manifestRef, manifestDeployOpts())
+		printInstallResults(results)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't deploy manifest '%s'", manifest)
 		}
@@ -82,6 +112,111 @@ func deployManifest(manifests []string) error {
 	return nil
 }
 
+// deployManifestContexts deploys the manifests to the cluster of each kubeconfig context, running up to
+// --cluster-parallelism clusters at once. Output is prefixed with the context. A failure on one cluster doesn't stop
+// the others.
+func deployManifestContexts(manifests []string, overlays []*v1alpha1.Manifest, contexts []string) error {
+	ctx := context.Background()
+
+	clusters := managers.DeployClusters(ctx, contexts, clusterParallelism, os.Stdout, func(ctx context.Context, kubeContext string, out io.Writer) ([]managers.InstallResult, error) {
+		return deployManifestsTo(ctx, kubeContext, manifests, overlays, out)
+	})
+
+	failed := 0
+	for _, cluster := range clusters {
+		if len(cluster.Results) > 0 {
+			fmt.Printf("\n=== %s ===", cluster.Context)
+			printInstallResults(cluster.Results)
+		}
+		if cluster.Err != nil {
+			failed++
+		}
+	}
+	fmt.Println()
+	err := managers.WriteClusterResults(os.Stdout, clusters)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("couldn't deploy to %d of %d clusters", failed, len(clusters))
+	}
+	return nil
+}
+
+// deployManifestsTo deploys the manifests, in order, to the cluster of a kubeconfig context
+func deployManifestsTo(ctx context.Context, kubeContext string, manifests []string, overlays []*v1alpha1.Manifest, out io.Writer) ([]managers.InstallResult, error) {
+	c, err := newKBClient(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	manifestMgr := managers.NewManifestManager(c)
+
+	opts := manifestDeployOpts()
+	opts.Out = out
+
+	var allResults []managers.InstallResult
+	for _, manifest := range manifests {
+		manifestRef := managers.ManifestReference{
+			Name:      manifest,
+			Namespace: namespace,
+			Overlays:  overlays,
+			Selection: installSelection(),
+		}
+
+		results, err := manifestMgr.DeploySmoketest(ctx, manifestRef, opts)
+		allResults = append(allResults, results...)
+		if err != nil {
+			return allResults, errors.Wrapf(err, "couldn't deploy manifest '%s'", manifest)
+		}
+	}
+	return allResults, nil
+}
+
+func manifestDeployOpts() managers.ManifestDeployOpts {
+	return managers.ManifestDeployOpts{
+		ShowLogs:        showLogs,
+		Timeout:         time.Duration(timeoutSeconds) * time.Second,
+		Parallelism:     parallelism,
+		ContinueOnError: continueOnError,
+		Resume:          resume,
+	}
+}
+
+// addSelectionFlags adds the flags that limit a manifest operation to some of its installs
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&onlyInstalls, "only", "", nil, "only process these installs or bundles")
+	cmd.Flags().BoolVarP(&withDeps, "with-deps", "", false, "with --only, also process the installs they require")
+	cmd.Flags().BoolVarP(&withDependents, "with-dependents", "", false, "with --only, also process the installs that require them")
+}
+
+func installSelection() managers.InstallSelection {
+	return managers.InstallSelection{
+		Only:           onlyInstalls,
+		WithDeps:       withDeps,
+		WithDependents: withDependents,
+	}
+}
+
+// printInstallResults prints a table with the outcome of each install deployed from a manifest
+func printInstallResults(results []managers.InstallResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
+	fmt.Fprintf(w, "NAME\tLAYER\tRESULT\tDURATION\tERROR\n")
+	for _, result := range results {
+		errMsg := ""
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%v\t%s\n", result.Name, result.Layer, result.Result, result.Duration.Round(time.Second), errMsg)
+	}
+	w.Flush()
+}
+
 var deployBundleCmd = &cobra.Command{
 	Use:   "bundle",
 	Short: "Deploy Applications",
@@ -101,7 +236,7 @@ func deployBundle(installs []string) error {
 	for _, installName := range installs {
 		installRef := managers.InstallReference{
 			Name:      installName,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
 		}
 		err := deploySmoketestMgr.DeploySmoketest(ctx, installRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
 		if err != nil {
@@ -129,7 +264,7 @@ func deployRegistry(registries []string) error {
 	registryMgr := managers.NewRegistryManager(c)
 
 	for _, registryName := range registries {
-		err := registryMgr.Deploy(ctx, managers.RegistryRef{Name: registryName, Namespace: defaultNamespace})
+		err := registryMgr.Deploy(ctx, managers.RegistryRef{Name: registryName, Namespace: namespace})
 		if err != nil {
 			return errors.Wrapf(err, "couldn't deploy registry '%s'", registryName)
 		}
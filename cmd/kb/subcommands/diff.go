@@ -18,6 +18,7 @@ package subcommands
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"time"
@@ -27,9 +28,15 @@ import (
 	"github.com/splunk/kube-bundler/managers"
 )
 
+var diffFormat string
+
 func init() {
-	diffManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy and smoketest logs")
+	diffManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show the full output of each diff job instead of only the patch")
 	diffManifestCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+	diffManifestCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "maximum number of diff jobs to run at once")
+	diffManifestCmd.Flags().StringVarP(&diffFormat, "format", "", managers.DiffFormatText, "output format: text, json or patch")
+	addSelectionFlags(diffManifestCmd)
+	addOverlayFlag(diffManifestCmd)
 
 	diffBundleCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 60, "timeout in seconds")
 
@@ -59,20 +66,42 @@ var diffManifestCmd = &cobra.Command{
 }
 
 func diffManifest(manifests []string) error {
+	switch diffFormat {
+	case managers.DiffFormatText, managers.DiffFormatJSON, managers.DiffFormatPatch:
+	default:
+		return fmt.Errorf("unknown format '%s'; expected text, json or patch", diffFormat)
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	manifestMgr := managers.NewManifestManager(c)
 
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
 	for _, manifest := range manifests {
 		manifestRef := managers.ManifestReference{
 			Name:      manifest,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
+			Overlays:  overlays,
+			Selection: installSelection(),
+		}
+
+		diffOpts := managers.ManifestDiffOpts{
+			Timeout:     time.Duration(timeoutSeconds) * time.Second,
+			Parallelism: parallelism,
 		}
+		diffs, diffErr := manifestMgr.Diff(ctx, manifestRef, diffOpts)
 
-		err := manifestMgr.Diff(ctx, manifestRef, time.Duration(timeoutSeconds)*time.Second)
+		err := managers.WriteInstallDiffs(os.Stdout, diffs, diffFormat, showLogs)
 		if err != nil {
-			return errors.Wrapf(err, "couldn't diff manifest '%s'", manifest)
+			return err
+		}
+		if diffErr != nil {
+			return errors.Wrapf(diffErr, "couldn't diff manifest '%s'", manifest)
 		}
 	}
 
@@ -98,7 +127,7 @@ func diffBundle(installs []string) error {
 	for _, installName := range installs {
 		installRef := managers.InstallReference{
 			Name:      installName,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
 		}
 
 		deployOpts := managers.DeployOpts{
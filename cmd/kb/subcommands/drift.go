@@ -0,0 +1,113 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/managers"
+)
+
+// Each command has its own variables, since pflag writes a flag's default into its variable when the flag is defined
+var (
+	serverDriftInterval  int
+	serverDriftTimeout   int
+	serverRemediateDrift bool
+	driftOnce            bool
+	metricsAddress       string
+
+	healthDriftInterval  int
+	healthRemediateDrift bool
+)
+
+func init() {
+	driftCmd.Flags().IntVarP(&serverDriftInterval, "interval", "", 600, "seconds between drift checks")
+	driftCmd.Flags().BoolVarP(&driftOnce, "once", "", false, "check every install once, print the results and exit")
+	driftCmd.Flags().BoolVarP(&serverRemediateDrift, "remediate", "", false, "re-apply installs that drifted")
+	driftCmd.Flags().IntVarP(&serverDriftTimeout, "timeout", "t", 300, "timeout in seconds of each diff and apply job")
+	driftCmd.Flags().StringVarP(&metricsAddress, "metrics-address", "", ":9090", "address to serve prometheus metrics on; empty to disable")
+
+	healthStatusCmd.Flags().IntVarP(&healthDriftInterval, "drift-interval", "", 0, "seconds between drift checks; 0 to disable drift detection")
+	healthStatusCmd.Flags().BoolVarP(&healthRemediateDrift, "remediate", "", false, "re-apply installs that drifted")
+
+	serverCmd.AddCommand(driftCmd)
+}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Periodically check installs for changes made outside of kb",
+	Long: `Periodically runs the diff action of every deployed install and records in the install's status whether its
+live resources differ from the resources rendered by its bundle. The kb_install_drift metric is 1 for installs
+that drifted. With --remediate, drifted installs are re-applied.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serverDriftInterval <= 0 && !driftOnce {
+			return fmt.Errorf("--interval must be positive, got %d", serverDriftInterval)
+		}
+
+		c := setup()
+
+		ctx := context.Background()
+		driftMgr := managers.NewDriftManager(c)
+		driftOpts := managers.DriftOpts{
+			Timeout:   time.Duration(serverDriftTimeout) * time.Second,
+			Remediate: serverRemediateDrift,
+		}
+
+		if driftOnce {
+			results, err := driftMgr.CheckAll(ctx, namespace, driftOpts)
+			if err != nil {
+				return err
+			}
+			err = managers.WriteDriftResults(os.Stdout, results)
+			if err != nil {
+				return err
+			}
+			for _, result := range results {
+				if result.Result == managers.DriftError {
+					return fmt.Errorf("couldn't check all installs for drift")
+				}
+			}
+			return nil
+		}
+
+		if metricsAddress != "" {
+			// Listen before starting the checks, so an address that's in use fails the command
+			listener, err := net.Listen("tcp", metricsAddress)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't serve metrics on %q", metricsAddress)
+			}
+			go func() {
+				mux := http.NewServeMux()
+				mux.Handle("/metrics", promhttp.Handler())
+				err := http.Serve(listener, mux)
+				log.WithFields(log.Fields{"address": metricsAddress, "err": err}).Fatal("Metrics server stopped")
+			}()
+		}
+
+		driftMgr.Run(ctx, namespace, time.Duration(serverDriftInterval)*time.Second, driftOpts)
+		return nil
+	},
+}
@@ -0,0 +1,105 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/managers"
+)
+
+var graphFormat string
+
+func init() {
+	graphManifestCmd.Flags().StringVarP(&graphFormat, "format", "", managers.GraphFormatASCII, "output format: ascii, dot or mermaid")
+	addOverlayFlag(graphManifestCmd)
+	graphInstallsCmd.Flags().StringVarP(&graphFormat, "format", "", managers.GraphFormatASCII, "output format: ascii, dot or mermaid")
+
+	graphCmd.AddCommand(graphManifestCmd)
+	graphCmd.AddCommand(graphInstallsCmd)
+	rootCmd.AddCommand(graphCmd)
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Show dependency graphs",
+	Long:  "Show dependency graphs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var graphManifestCmd = &cobra.Command{
+	Use:   "manifest <name>",
+	Short: "Show the dependency graph of a manifest's installs",
+	Long:  "Show the dependency graph of a manifest's installs, including suffixed installs, with the layer, version and status of each install",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return graphManifest(args[0])
+	},
+}
+
+func graphManifest(manifestName string) error {
+	c := setup()
+
+	ctx := context.Background()
+	graphMgr := managers.NewGraphManager(c)
+
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
+	manifestRef := managers.ManifestReference{
+		Name:      manifestName,
+		Namespace: namespace,
+		Overlays:  overlays,
+	}
+	graph, err := graphMgr.ManifestGraph(ctx, manifestRef)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get graph of manifest '%s'", manifestName)
+	}
+
+	return graph.Write(os.Stdout, graphFormat)
+}
+
+var graphInstallsCmd = &cobra.Command{
+	Use:   "installs",
+	Short: "Show the dependency graph of all installs",
+	Long:  "Show the dependency graph of all installs, with the layer, version and status of each install",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return graphInstalls()
+	},
+}
+
+func graphInstalls() error {
+	c := setup()
+
+	ctx := context.Background()
+	graphMgr := managers.NewGraphManager(c)
+
+	graph, err := graphMgr.InstallsGraph(ctx, namespace)
+	if err != nil {
+		return errors.Wrap(err, "couldn't get graph of installs")
+	}
+
+	return graph.Write(os.Stdout, graphFormat)
+}
@@ -38,6 +38,9 @@ func init() {
 	//importBundleCmd.Flags().StringVarP(&sourceArg, "source", "s", "", "name of source to import from")
 	importManifestCmd.Flags().StringVarP(&destDirArg, "dest-dir", "d", "", "base registry directory; use for fast import directly to local host filesystem")
 	importManifestCmd.Flags().StringVarP(&hostArg, "host", "h", "", "host IP of the node that is running the registry pod to import into")
+	addSelectionFlags(importManifestCmd)
+	addOverlayFlag(importManifestCmd)
+	importManifestCmd.Flags().StringVarP(&lockFilename, "lockfile", "", "", "import the bundle versions and digests in a lockfile written by kb manifest lock")
 
 	importCmd.AddCommand(importBundleCmd)
 	importCmd.AddCommand(importManifestCmd)
@@ -78,7 +81,7 @@ func importBundle(registryName string, destDir string, hostArg string, bundles [
 		bundleRefs = append(bundleRefs, managers.BundleRef{Name: bundleFile.Name, Version: bundleFile.Version})
 	}
 
-	err := registryMgr.Import(ctx, managers.RegistryRef{Name: registryName, Namespace: defaultNamespace}, bundleSource, bundleRefs, destDir, hostArg)
+	err := registryMgr.Import(ctx, managers.RegistryRef{Name: registryName, Namespace: namespace}, bundleSource, bundleRefs, destDir, hostArg)
 	if err != nil {
 		return errors.Wrap(err, "couldn't import bundles")
 	}
@@ -101,8 +104,17 @@ func importManifest(destDir string, hostArg string, manifestNames []string) erro
 	ctx := context.Background()
 	registryMgr := managers.NewRegistryManager(c)
 
+	lock, err := loadLock(lockFilename)
+	if err != nil {
+		return err
+	}
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
 	for _, manifestName := range manifestNames {
-		manifestRef := managers.ManifestReference{Name: manifestName, Namespace: defaultNamespace}
+		manifestRef := managers.ManifestReference{Name: manifestName, Namespace: namespace, Overlays: overlays, Lock: lock, Selection: installSelection()}
 		err := registryMgr.ImportManifest(ctx, manifestRef, destDir, hostArg)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't import manifest '%s'", manifestName)
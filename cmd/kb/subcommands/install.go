@@ -18,6 +18,7 @@ package subcommands
 
 import (
 	"context"
+	"os"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,7 +35,12 @@ func init() {
 	installManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy and smoketest logs")
 	installManifestCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
 	installManifestCmd.Flags().BoolVarP(&skipSmoketests, "skip-smoketests", "", false, "skip smoketests")
+	installManifestCmd.Flags().IntVarP(&parallelism, "parallelism", "p", 4, "maximum number of installs to deploy at once within a dependency layer")
+	installManifestCmd.Flags().BoolVarP(&continueOnError, "continue-on-error", "", false, "keep deploying installs that don't depend on a failed install")
+	installManifestCmd.Flags().BoolVarP(&resume, "resume", "", false, "skip installs whose inputs haven't changed since their last successful deploy")
 	installManifestCmd.Flags().BoolP("force", "f", false, "Force installation even if node count does not meet flavor requirement")
+	installManifestCmd.Flags().StringVarP(&lockFilename, "lockfile", "", "", "install the bundle versions and digests in a lockfile written by kb manifest lock")
+	addOverlayFlag(installManifestCmd)
 
 	installCmd.AddCommand(installBundleCmd)
 	installCmd.AddCommand(installManifestCmd)
@@ -73,7 +79,7 @@ func installBundle(bundles []string, force bool) error {
 	dockerUrl := ""
 	if registryArg != "" {
 		var registry v1alpha1.Registry
-		err := resourceMgr.Get(ctx, registryArg, defaultNamespace, &registry)
+		err := resourceMgr.Get(ctx, registryArg, namespace, &registry)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't get registry %q", registryArg)
 		}
@@ -92,7 +98,7 @@ func installBundle(bundles []string, force bool) error {
 		bundleRefs = append(bundleRefs, managers.BundleRef{Name: bundleFile.Name, Version: bundleFile.Version})
 	}
 
-	apps, err := registerMgr.RegisterAll(ctx, bundleRefs, bundleSource, defaultNamespace)
+	apps, err := registerMgr.RegisterAll(ctx, bundleRefs, bundleSource, namespace)
 	if err != nil {
 		return errors.Wrap(err, "couldn't register bundles")
 	}
@@ -103,14 +109,14 @@ func installBundle(bundles []string, force bool) error {
 		// TODO: provide ability to set parameters, suffix, and registry
 		suffix := ""
 		parameters := []v1alpha1.ParameterSpec{}
-		install, err := installMgr.Install(ctx, app.Spec.Name, app.Spec.Name, defaultNamespace, app.Spec.Version, suffix, defaultFlavor, dockerUrl, force, parameters)
+		install, err := installMgr.Install(ctx, app.Spec.Name, app.Spec.Name, namespace, app.Spec.Version, suffix, defaultFlavor, dockerUrl, force, parameters)
 		if err != nil {
 			return errors.Wrapf(err, "could install application %s", app.Spec.Name)
 		}
 
 		installRef := managers.InstallReference{
 			Name:      install.Name,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
 		}
 		err = deploySmoketestMgr.DeploySmoketest(ctx, installRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
 		if err != nil {
@@ -122,9 +128,9 @@ func installBundle(bundles []string, force bool) error {
 }
 
 var installManifestCmd = &cobra.Command{
-	Use:   "manifest",
+	Use:   "manifest <name|file>...",
 	Short: "Install manifests",
-	Long:  "Install manifests",
+	Long:  "Install manifests. A manifest file is saved as a manifest resource before it's installed.",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		force, err := cmd.Flags().GetBool("force")
@@ -141,10 +147,34 @@ func manifestInstall(manifests []string, force bool) error {
 	ctx := context.Background()
 	manifestMgr := managers.NewManifestManager(c)
 
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+	lock, err := loadLock(lockFilename)
+	if err != nil {
+		return err
+	}
+
 	for _, manifest := range manifests {
 		manifestRef := managers.ManifestReference{
 			Name:      manifest,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
+			Overlays:  overlays,
+			Lock:      lock,
+		}
+
+		// Save manifest files as resources, so they can be deployed by name later
+		if isFile(manifest) {
+			m, err := managers.LoadManifestFile(manifest)
+			if err != nil {
+				return err
+			}
+			err = manifestMgr.Save(ctx, m, namespace)
+			if err != nil {
+				return err
+			}
+			manifestRef.Name = m.Name
 		}
 
 		err := manifestMgr.Install(ctx, manifestRef, force)
@@ -152,18 +182,41 @@ func manifestInstall(manifests []string, force bool) error {
 			return errors.Wrapf(err, "couldn't install manifest '%s'", manifest)
 		}
 
+		var results []managers.InstallResult
 		if !skipSmoketests {
-			err = manifestMgr.DeploySmoketest(ctx, manifestRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
-			if err != nil {
-				return errors.Wrapf(err, "couldn't deploy manifest '%s'", manifest)
-			}
+			results, err = manifestMgr.DeploySmoketest(ctx, manifestRef, manifestDeployOpts())
 		} else {
-			err = manifestMgr.Deploy(ctx, manifestRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
-			if err != nil {
-				return errors.Wrapf(err, "couldn't deploy manifest '%s'", manifest)
-			}
+			results, err = manifestMgr.Deploy(ctx, manifestRef, manifestDeployOpts())
+		}
+		printInstallResults(results)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't deploy manifest '%s'", manifest)
 		}
 	}
 
 	return nil
 }
+
+// addOverlayFlag adds the flag that merges overlay files over a manifest. Commands that act on a manifest take the same
+// overlays as the install, so that bundles and parameters added by an overlay aren't dropped.
+func addOverlayFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&overlayFiles, "overlay", "", nil, "manifest file to merge over the manifest; may be repeated, later files take precedence")
+}
+
+// loadOverlays reads the manifest files given with --overlay
+func loadOverlays(filenames []string) ([]*v1alpha1.Manifest, error) {
+	var overlays []*v1alpha1.Manifest
+	for _, filename := range filenames {
+		overlay, err := managers.LoadManifestFile(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't load overlay")
+		}
+		overlays = append(overlays, overlay)
+	}
+	return overlays, nil
+}
+
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
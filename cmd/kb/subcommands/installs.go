@@ -19,13 +19,17 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/splunk/kube-bundler/api/v1alpha1"
 	"github.com/splunk/kube-bundler/managers"
+	"k8s.io/apimachinery/pkg/util/duration"
 )
 
 const (
@@ -47,9 +51,12 @@ var (
 )
 
 func init() {
-	createInstallCmd.Flags().StringVarP(&installOpts.Name, "name", "n", "", "application to install")
+	createInstallCmd.Flags().StringVarP(&installOpts.Name, "name", "", "", "application to install")
 	createInstallCmd.Flags().StringVarP(&installOpts.Version, "version", "v", "", "version to install")
 
+	addOutputFlag(getInstalls)
+	addOutputFlag(descInstallCmd)
+
 	getCmd.AddCommand(getInstalls)
 	describeCmd.AddCommand(descInstallCmd)
 	createCmd.AddCommand(createInstallCmd)
@@ -67,24 +74,54 @@ var getInstalls = &cobra.Command{
 }
 
 func listInstalls(args []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	mgr := managers.NewResourceManager(c)
 	var list v1alpha1.InstallList
-	err := mgr.List(ctx, defaultNamespace, &list)
+	err = mgr.List(ctx, namespace, &list)
 	if err != nil {
 		return errors.Wrap(err, "couldn't list installs")
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-	fmt.Fprintf(w, "NAME\tAPPLICATION\tVERSION\t\n")
-
+	names := make([]string, 0, len(list.Items))
 	for _, install := range list.Items {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", install.Name, install.Spec.Application, install.Spec.Version)
+		names = append(names, install.Name)
 	}
 
-	return w.Flush()
+	return p.Print(os.Stdout, &list, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		if wide {
+			fmt.Fprintf(w, "NAME\tAPPLICATION\tVERSION\tSUFFIX\tFLAVOR\tLAST APPLIED\tDRIFTED\n")
+		} else {
+			fmt.Fprintf(w, "NAME\tAPPLICATION\tVERSION\t\n")
+		}
+
+		for _, install := range list.Items {
+			if !wide {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", install.Name, install.Spec.Application, install.Spec.Version)
+				continue
+			}
+
+			lastApplied := "<none>"
+			if install.Status.LastApplied != nil {
+				lastApplied = fmt.Sprintf("%s, %s ago", install.Status.LastApplied.Version, duration.HumanDuration(time.Since(install.Status.LastApplied.Time.Time)))
+			}
+			drifted := "<unknown>"
+			if install.Status.Drift != nil {
+				drifted = fmt.Sprintf("%t", install.Status.Drift.Drifted)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", install.Name, install.Spec.Application, install.Spec.Version,
+				install.Spec.Suffix, install.Spec.Flavor, lastApplied, drifted)
+		}
+
+		return w.Flush()
+	})
 }
 
 var descInstallCmd = &cobra.Command{
@@ -92,41 +129,69 @@ var descInstallCmd = &cobra.Command{
 	Aliases: useInstallsAlias,
 	Short:   "Describe installs",
 	Long:    "Describe installs",
-	Args:    cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return describeInstalls(args)
 	},
 }
 
 func describeInstalls(installs []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	im := managers.NewInstallManager(c)
 
-	installDescriptions, err := im.Describe(ctx, installs)
+	installDescriptions, err := im.Describe(ctx, namespace, installs)
 	if err != nil {
 		return errors.Wrap(err, "failed to describe installs")
 	}
 
+	names := make([]string, 0, len(installDescriptions))
 	for _, desc := range installDescriptions {
-		fmt.Printf("Install Name: %s\n", desc.Name)
-		fmt.Printf("Application: %s\n", desc.Application)
-		fmt.Printf("Version: %s\n", desc.Version)
-		fmt.Printf("\nParameters\n==========\n")
-
-		w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-		fmt.Fprintf(w, "NAME\tCURRENT VALUE\tDEFAULT\tDESCRIPTION\n")
-
-		for name, params := range desc.Parameters {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, params.Value, params.Default, params.Description)
-		}
-
-		w.Flush()
-		fmt.Println()
+		names = append(names, desc.Name)
 	}
 
-	return nil
+	return p.Print(os.Stdout, installDescriptions, names, func(out io.Writer, wide bool) error {
+		for _, desc := range installDescriptions {
+			fmt.Fprintf(out, "Install Name: %s\n", desc.Name)
+			fmt.Fprintf(out, "Application: %s\n", desc.Application)
+			fmt.Fprintf(out, "Version: %s\n", desc.Version)
+			fmt.Fprintf(out, "\nParameters\n==========\n")
+
+			parameterNames := make([]string, 0, len(desc.Parameters))
+			for name := range desc.Parameters {
+				parameterNames = append(parameterNames, name)
+			}
+			sort.Strings(parameterNames)
+
+			w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+			if wide {
+				fmt.Fprintf(w, "NAME\tTYPE\tREQUIRED\tSENSITIVE\tCURRENT VALUE\tDEFAULT\tDESCRIPTION\n")
+			} else {
+				fmt.Fprintf(w, "NAME\tTYPE\tREQUIRED\tCURRENT VALUE\tDEFAULT\tDESCRIPTION\n")
+			}
+
+			for _, name := range parameterNames {
+				params := desc.Parameters[name]
+				if wide {
+					fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\t%s\n", name, params.Type, params.Required, params.Sensitive, params.Value, params.Default, params.Description)
+				} else {
+					fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\n", name, params.Type, params.Required, params.Value, params.Default, params.Description)
+				}
+			}
+
+			err := w.Flush()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out)
+		}
+		return nil
+	})
 }
 
 var createInstallCmd = &cobra.Command{
@@ -149,7 +214,7 @@ func createInstall(appName string, opts InstallOpts) error {
 	// TODO: provide ability to set parameters, suffix, and registry
 	suffix := ""
 	parameters := []v1alpha1.ParameterSpec{}
-	_, err := installMgr.Install(ctx, appName, opts.Name, defaultNamespace, opts.Version, suffix, defaultFlavor, "", false, parameters)
+	_, err := installMgr.Install(ctx, appName, opts.Name, namespace, opts.Version, suffix, defaultFlavor, "", false, parameters)
 	if err != nil {
 		return errors.Wrap(err, "couldn't create install")
 	}
@@ -176,7 +241,7 @@ func deleteInstalls(installs []string) error {
 
 	for _, installName := range installs {
 		var install v1alpha1.Install
-		err := mgr.Delete(ctx, installName, defaultNamespace, &install)
+		err := mgr.Delete(ctx, installName, namespace, &install)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't delete install %q", installName)
 		}
@@ -0,0 +1,131 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/managers"
+)
+
+func init() {
+	lockManifestCmd.Flags().StringVarP(&lockFilename, "lockfile", "", "", "lockfile to write; defaults to <manifest>.lock.yaml")
+	addOverlayFlag(lockManifestCmd)
+
+	manifestCmd.AddCommand(lockManifestCmd)
+	manifestCmd.AddCommand(validateManifestCmd)
+	rootCmd.AddCommand(manifestCmd)
+}
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Work with manifests",
+	Long:  "Work with manifests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var lockManifestCmd = &cobra.Command{
+	Use:   "lock <manifest>",
+	Short: "Write a manifest lockfile",
+	Long:  "Resolve every bundle of a manifest from its sources and write the exact versions and digests to a lockfile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lockManifest(args[0])
+	},
+}
+
+func lockManifest(manifestName string) error {
+	c := setup()
+
+	ctx := context.Background()
+	manifestMgr := managers.NewManifestManager(c)
+
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
+	manifestRef := managers.ManifestReference{
+		Name:      manifestName,
+		Namespace: namespace,
+		Overlays:  overlays,
+	}
+	lock, err := manifestMgr.Lock(ctx, manifestRef)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't lock manifest '%s'", manifestName)
+	}
+
+	filename := lockFilename
+	if filename == "" {
+		filename = manifestName + ".lock.yaml"
+	}
+	err = managers.WriteManifestLock(filename, lock)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Locked %d bundles in %s\n", len(lock.Bundles), filename)
+	return nil
+}
+
+var validateManifestCmd = &cobra.Command{
+	Use:   "validate <file>...",
+	Short: "Validate manifest files",
+	Long:  "Check manifest files against their sources and the applications of their bundles, and print every problem found",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateManifest(args)
+	},
+}
+
+func validateManifest(filenames []string) error {
+	c := setup()
+
+	ctx := context.Background()
+	manifestMgr := managers.NewManifestManager(c)
+
+	count := 0
+	for _, filename := range filenames {
+		problems, err := manifestMgr.ValidateFile(ctx, filename, namespace)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't validate manifest '%s'", filename)
+		}
+		for _, problem := range problems {
+			fmt.Printf("%s:%s\n", filename, problem)
+		}
+		count += len(problems)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("found %d problems", count)
+	}
+	fmt.Println("Manifest is valid")
+	return nil
+}
+
+// loadLock reads the lockfile given with --lockfile, if any
+func loadLock(filename string) (*managers.ManifestLock, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	return managers.LoadManifestLock(filename)
+}
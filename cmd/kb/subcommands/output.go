@@ -0,0 +1,34 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/helpers/printer"
+)
+
+var outputFormat string
+
+// addOutputFlag adds the -o flag that selects how a command prints its results
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "", "output format: "+printer.Formats)
+}
+
+// newPrinter returns a printer for the format given with -o
+func newPrinter() (*printer.Printer, error) {
+	return printer.New(outputFormat)
+}
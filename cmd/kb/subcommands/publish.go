@@ -73,7 +73,7 @@ func publish(configFilename string, filenames []string) error {
 		return errors.Wrapf(err, "couldn't create source instance '%s'", sourceConfig.Name)
 	}
 
-	err = publishMgr.Publish(ctx, source, defaultNamespace, filenames)
+	err = publishMgr.Publish(ctx, source, namespace, filenames)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't publish bundle")
 	}
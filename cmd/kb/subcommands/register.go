@@ -54,7 +54,7 @@ func register(bundles []string) error {
 		bundleRefs = append(bundleRefs, managers.BundleRef{Name: bundleFile.Name, Version: bundleFile.Version})
 	}
 
-	_, err := mgr.RegisterAll(ctx, bundleRefs, bundleSource, defaultNamespace)
+	_, err := mgr.RegisterAll(ctx, bundleRefs, bundleSource, namespace)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't register bundles")
 	}
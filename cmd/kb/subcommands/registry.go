@@ -45,7 +45,7 @@ func deleteRegistry(registries []string) error {
 	registryMgr := managers.NewRegistryManager(c)
 
 	for _, registryName := range registries {
-		err := registryMgr.Delete(ctx, managers.RegistryRef{Name: registryName, Namespace: defaultNamespace})
+		err := registryMgr.Delete(ctx, managers.RegistryRef{Name: registryName, Namespace: namespace})
 		if err != nil {
 			return errors.Wrapf(err, "couldn't delete registry '%s'", registryName)
 		}
@@ -31,13 +31,19 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
 	debug bool
 	info  bool
+
+	namespace   string
+	kubeconfig  string
+	kubeContext string
+
+	encryptionKeyFile   string
+	encryptionKeySecret string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -73,6 +79,11 @@ func init() {
 	// will be global for your application.
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "debug output")
 	rootCmd.PersistentFlags().BoolVar(&info, "info", false, "info output")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file; defaults to $KUBECONFIG or ~/.kube/config")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig context to use; defaults to the current context")
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", envOrDefault("KB_NAMESPACE", defaultNamespace), "namespace of the kb resources to operate on")
+	rootCmd.PersistentFlags().StringVar(&encryptionKeyFile, "encryption-key-file", os.Getenv("KB_ENCRYPTION_KEY_FILE"), "file holding the keys that encrypt generated secrets")
+	rootCmd.PersistentFlags().StringVar(&encryptionKeySecret, "encryption-key-secret", os.Getenv("KB_ENCRYPTION_KEY_SECRET"), "secret in the kb namespace holding the keys that encrypt generated secrets")
 	//rootCmd.PersistentFlags().BoolP("help", "h", false, "Help message")
 
 	// Cobra also supports local flags, which will only run
@@ -80,6 +91,14 @@ func init() {
 	// rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// envOrDefault returns the value of an environment variable, or def if it isn't set
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
 func initConfig() {
 	// Setup logger
 	log.SetFormatter(&log.TextFormatter{
@@ -97,40 +116,70 @@ func initConfig() {
 }
 
 func setup() managers.KBClient {
+	kbClient, err := newKBClient(kubeContext)
+	if err != nil {
+		log.WithField("err", err).Fatal("couldn't create kubernetes clients")
+	}
+	return kbClient
+}
+
+// newKBClient creates the clients for a kubeconfig context, or for the current context if kubeContext is empty. The
+// controller-runtime and client-go clients share a single rest config.
+func newKBClient(kubeContext string) (managers.KBClient, error) {
 	scheme := runtime.NewScheme()
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = v1alpha1.AddToScheme(scheme)
 	_ = apiextensionsv1.AddToScheme(scheme)
 
-	kubeconfig, err := ctrl.GetConfig()
-	if err != nil {
-		log.WithField("err", err).Fatal("couldn't get controller-runtime client")
-	}
-	c, err := client.New(kubeconfig, client.Options{Scheme: scheme})
+	restConfig, err := createRestConfig(kubeconfig, kubeContext)
 	if err != nil {
-		log.WithField("err", err).Fatal("couldn't create controller-runtime client")
+		return managers.KBClient{}, err
 	}
 
-	restConfig, err := createDefaultRestConfig()
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
 	if err != nil {
-		log.WithField("err", err).Fatal("couldn't create default rest client")
+		return managers.KBClient{}, errors.Wrap(err, "couldn't create controller-runtime client")
 	}
 
 	cs, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
-		log.WithField("err", err).Fatal("couldn't create client-go client")
+		return managers.KBClient{}, errors.Wrap(err, "couldn't create client-go client")
 	}
 
-	return managers.KBClient{Client: c, Interface: cs, RestConfig: restConfig}
+	kbClient := managers.KBClient{Client: c, Interface: cs, RestConfig: restConfig}
+
+	switch {
+	case encryptionKeyFile != "" && encryptionKeySecret != "":
+		return managers.KBClient{}, errors.New("only one of --encryption-key-file and --encryption-key-secret may be set")
+	case encryptionKeyFile != "":
+		keyProvider, err := managers.NewLocalKeyProvider(encryptionKeyFile)
+		if err != nil {
+			return managers.KBClient{}, errors.Wrap(err, "couldn't load encryption keys")
+		}
+		kbClient.KeyProvider = keyProvider
+	case encryptionKeySecret != "":
+		kbClient.KeyProvider = managers.NewSecretKeyProvider(kbClient, namespace, encryptionKeySecret)
+	}
+
+	return kbClient, nil
 }
 
-func createDefaultRestConfig() (*rest.Config, error) {
+// createRestConfig loads the rest config of a kubeconfig context. An empty kubeconfig follows the default loading rules,
+// and an empty context uses the current context. Without a kubeconfig, the in-cluster config is used.
+func createRestConfig(kubeconfig, kubeContext string) (*rest.Config, error) {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	defaultKubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	restConfig, err := defaultKubeconfig.ClientConfig()
+	loadingRules.ExplicitPath = kubeconfig
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+	restConfig, err := clientConfig.ClientConfig()
 	if err != nil {
-		return nil, errors.Wrap(err, "Unable to initialize REST config with default kubeconfig")
+		return nil, errors.Wrap(err, "Unable to initialize REST config from kubeconfig")
+	}
+
+	// Match the client-side rate limits of controller-runtime's config loader
+	if restConfig.QPS == 0 {
+		restConfig.QPS = 20
+		restConfig.Burst = 30
 	}
 	return restConfig, nil
 }
@@ -0,0 +1,185 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package subcommands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/managers"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var (
+	skipDeploy bool
+	rotateKey  bool
+)
+
+func init() {
+	rotateSecretsCmd.Flags().BoolVarP(&skipDeploy, "skip-deploy", "", false, "don't redeploy the install after rotating")
+	rotateSecretsCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy logs")
+	rotateSecretsCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+
+	reencryptSecretsCmd.Flags().BoolVarP(&rotateKey, "rotate-key", "", false, "create a new encryption key before reencrypting (--encryption-key-secret only)")
+
+	addOutputFlag(listSecretsCmd)
+
+	secretsCmd.AddCommand(listSecretsCmd)
+	secretsCmd.AddCommand(rotateSecretsCmd)
+	secretsCmd.AddCommand(reencryptSecretsCmd)
+
+	rootCmd.AddCommand(secretsCmd)
+}
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage generated secrets",
+	Long:  "Manage generated secrets",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var listSecretsCmd = &cobra.Command{
+	Use:   "list [install...]",
+	Short: "List generated secrets",
+	Long:  "List generated secrets and their age, for all installs or the given installs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listSecrets(args)
+	},
+}
+
+func listSecrets(installs []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
+	c := setup()
+
+	ctx := context.Background()
+	secretsMgr := managers.NewSecretsManager(c)
+
+	secrets, err := secretsMgr.List(ctx, namespace, installs)
+	if err != nil {
+		return errors.Wrap(err, "couldn't list generated secrets")
+	}
+
+	names := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		names = append(names, fmt.Sprintf("%s/%s", secret.Install, secret.Parameter))
+	}
+
+	return p.Print(os.Stdout, secrets, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		if wide {
+			fmt.Fprintf(w, "INSTALL\tPARAMETER\tAGE\tGENERATED AT\tPREVIOUS\n")
+		} else {
+			fmt.Fprintf(w, "INSTALL\tPARAMETER\tAGE\tPREVIOUS\n")
+		}
+		for _, secret := range secrets {
+			age := "<unknown>"
+			generatedAt := "<unknown>"
+			if !secret.GeneratedAt.IsZero() {
+				age = duration.HumanDuration(time.Since(secret.GeneratedAt))
+				generatedAt = secret.GeneratedAt.UTC().Format(time.RFC3339)
+			}
+			previous := ""
+			if secret.HasPrevious {
+				previous = "pending deploy"
+			}
+			if wide {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", secret.Install, secret.Parameter, age, generatedAt, previous)
+			} else {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", secret.Install, secret.Parameter, age, previous)
+			}
+		}
+		return w.Flush()
+	})
+}
+
+var rotateSecretsCmd = &cobra.Command{
+	Use:   "rotate <install> [param...]",
+	Short: "Rotate generated secrets",
+	Long:  "Regenerate the generated secrets of an install, or only the given parameters, and redeploy the install. The previous values are available to the deploy as <param>_previous.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rotateSecrets(args[0], args[1:])
+	},
+}
+
+func rotateSecrets(installName string, parameters []string) error {
+	c := setup()
+
+	ctx := context.Background()
+	secretsMgr := managers.NewSecretsManager(c)
+	installRef := managers.InstallReference{
+		Name:      installName,
+		Namespace: namespace,
+	}
+
+	rotated, err := secretsMgr.Rotate(ctx, installRef, parameters)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't rotate secrets for install '%s'", installName)
+	}
+	for _, name := range rotated {
+		fmt.Printf("Rotated %s\n", name)
+	}
+
+	if skipDeploy || len(rotated) == 0 {
+		return nil
+	}
+
+	deploySmoketestMgr := managers.NewDeploySmoketestManager(c)
+	err = deploySmoketestMgr.DeploySmoketest(ctx, installRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't redeploy install '%s'", installName)
+	}
+
+	return nil
+}
+
+var reencryptSecretsCmd = &cobra.Command{
+	Use:   "reencrypt",
+	Short: "Reencrypt generated secrets with the current encryption key",
+	Long:  "Reencrypt generated secrets with the current encryption key, including secrets stored before encryption was enabled",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reencryptSecrets()
+	},
+}
+
+func reencryptSecrets() error {
+	c := setup()
+
+	ctx := context.Background()
+	secretsMgr := managers.NewSecretsManager(c)
+
+	count, err := secretsMgr.Reencrypt(ctx, namespace, rotateKey)
+	if err != nil {
+		return errors.Wrap(err, "couldn't reencrypt secrets")
+	}
+
+	fmt.Printf("Reencrypted %d values\n", count)
+	return nil
+}
@@ -29,6 +29,8 @@ import (
 func init() {
 	smoketestManifestCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 300, "timeout in seconds")
 	smoketestManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy logs")
+	addSelectionFlags(smoketestManifestCmd)
+	addOverlayFlag(smoketestManifestCmd)
 
 	smoketestBundleCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 300, "timeout in seconds")
 	smoketestBundleCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show deploy logs")
@@ -63,10 +65,17 @@ func smoketestManifest(manifests []string) error {
 	ctx := context.Background()
 	manifestMgr := managers.NewManifestManager(c)
 
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
 	for _, manifest := range manifests {
 		manifestRef := managers.ManifestReference{
 			Name:      manifest,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
+			Overlays:  overlays,
+			Selection: installSelection(),
 		}
 
 		err := manifestMgr.Smoketest(ctx, manifestRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
@@ -94,7 +103,7 @@ func smoketestBundle(args []string) error {
 	ctx := context.Background()
 
 	for _, installName := range args {
-		installRef := managers.InstallReference{Name: installName, Namespace: defaultNamespace}
+		installRef := managers.InstallReference{Name: installName, Namespace: namespace}
 		smoketestMgr := managers.NewSmoketestManager(c)
 
 		err := smoketestMgr.Smoketest(ctx, installRef, showLogs, time.Duration(timeoutSeconds)*time.Second)
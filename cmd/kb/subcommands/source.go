@@ -19,6 +19,7 @@ package subcommands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"text/tabwriter"
 
@@ -41,10 +42,12 @@ type SourceOpts struct {
 }
 
 func init() {
-	createSourceCmd.Flags().StringVarP(&sourceOpts.Name, "name", "n", "", "name of source")
+	createSourceCmd.Flags().StringVarP(&sourceOpts.Name, "name", "", "", "name of source")
 	createSourceCmd.Flags().StringVarP(&sourceOpts.Type, "type", "t", "", "type of source")
 	createSourceCmd.Flags().StringVarP(&sourceOpts.Path, "path", "p", "", "path of source")
 
+	addOutputFlag(getSourcesCmd)
+
 	getCmd.AddCommand(getSourcesCmd)
 	createCmd.AddCommand(createSourceCmd)
 	deleteCmd.AddCommand(deleteSourceCmd)
@@ -61,24 +64,37 @@ var getSourcesCmd = &cobra.Command{
 }
 
 func listSources(args []string) error {
+	p, err := newPrinter()
+	if err != nil {
+		return err
+	}
+
 	c := setup()
 
 	ctx := context.Background()
 	mgr := managers.NewResourceManager(c)
 	var list v1alpha1.SourceList
-	err := mgr.List(ctx, defaultNamespace, &list)
+	err = mgr.List(ctx, namespace, &list)
 	if err != nil {
 		return errors.Wrap(err, "couldn't list sources")
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-	fmt.Fprintf(w, "NAME\tTYPE\tPATH\tOPTIONS\n")
-
+	names := make([]string, 0, len(list.Items))
 	for _, source := range list.Items {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", source.Name, source.Spec.Type, source.Spec.Path, source.Spec.Options)
+		names = append(names, source.Name)
 	}
 
-	return w.Flush()
+	// Sources have no more columns to show with -o wide
+	return p.Print(os.Stdout, &list, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		fmt.Fprintf(w, "NAME\tTYPE\tPATH\tOPTIONS\n")
+
+		for _, source := range list.Items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", source.Name, source.Spec.Type, source.Spec.Path, source.Spec.Options)
+		}
+
+		return w.Flush()
+	})
 }
 
 var createSourceCmd = &cobra.Command{
@@ -89,6 +105,9 @@ var createSourceCmd = &cobra.Command{
 	Args:    cobra.ExactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if sourceOpts.Name == "" {
+			if cmd.Flags().Changed("namespace") {
+				return errors.New("empty source name: -n sets --namespace, use --name to name the source")
+			}
 			return errors.New("empty source name")
 		}
 		if sourceOpts.Type == "" {
@@ -110,7 +129,7 @@ func createSource(opts SourceOpts) error {
 
 	var source v1alpha1.Source
 	source.Name = opts.Name
-	source.Namespace = defaultNamespace
+	source.Namespace = namespace
 	source.Spec.Type = opts.Type
 	source.Spec.Path = opts.Path
 
@@ -140,7 +159,7 @@ func deleteSources(installs []string) error {
 
 	for _, sourceName := range installs {
 		var source v1alpha1.Source
-		err := mgr.Delete(ctx, sourceName, defaultNamespace, &source)
+		err := mgr.Delete(ctx, sourceName, namespace, &source)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't delete source %q", sourceName)
 		}
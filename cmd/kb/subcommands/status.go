@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/splunk/kube-bundler/helpers/printer"
 	"github.com/splunk/kube-bundler/managers"
 )
 
@@ -43,7 +45,13 @@ const (
 var appStatusMap = map[int]string{0: "down", 1: "up"}
 
 func init() {
+	addOutputFlag(statusCmd)
+	addOutputFlag(applicationStatusCmd)
+	addOutputFlag(resourceStatusCmd)
+
 	statusCmd.AddCommand(healthStatusCmd)
+	statusCmd.AddCommand(applicationStatusCmd)
+	statusCmd.AddCommand(resourceStatusCmd)
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(statusCmd)
 	serverCmd.AddCommand(healthStatusCmd)
@@ -66,7 +74,16 @@ var healthStatusCmd = &cobra.Command{
 		c := setup()
 		sm := managers.NewStatusManager(c)
 
-		return sm.HealthStatus()
+		if healthDriftInterval > 0 {
+			driftMgr := managers.NewDriftManager(c)
+			driftOpts := managers.DriftOpts{
+				Timeout:   300 * time.Second,
+				Remediate: healthRemediateDrift,
+			}
+			go driftMgr.Run(context.Background(), namespace, time.Duration(healthDriftInterval)*time.Second, driftOpts)
+		}
+
+		return sm.HealthStatus(namespace)
 	},
 }
 
@@ -75,8 +92,13 @@ var statusCmd = &cobra.Command{
 	Short: "Get bundle status",
 	Long:  "Get bundle status",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		c := setup()
-		return printStatus(c)
+		return printStatus(c, p)
 	},
 }
 
@@ -85,8 +107,13 @@ var applicationStatusCmd = &cobra.Command{
 	Short: "Get application status",
 	Long:  "Get application status",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		c := setup()
-		return printApplicationStatus(c)
+		return printApplicationStatus(c, p)
 	},
 }
 
@@ -95,6 +122,11 @@ var resourceStatusCmd = &cobra.Command{
 	Short: "Get resource status",
 	Long:  "Get resource status",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := newPrinter()
+		if err != nil {
+			return err
+		}
+
 		c := setup()
 
 		resources, err := getResourceStatus(c)
@@ -102,63 +134,106 @@ var resourceStatusCmd = &cobra.Command{
 			return err
 		}
 
-		return printResourceStatus(c, resources)
+		return printResourceStatus(p, resources)
 	},
 }
 
-func printStatus(kbClient managers.KBClient) error {
-	c := setup()
+// statusResult is the structured output of kb status. A check that couldn't be retrieved is left out and its error
+// is listed in Errors.
+type statusResult struct {
+	Applications *managers.AppStatusResponse `json:"applications,omitempty"`
+	Resources    []managers.ResourceStatus   `json:"resources,omitempty"`
+	Errors       []string                    `json:"errors,omitempty"`
+}
+
+func printStatus(kbClient managers.KBClient, p *printer.Printer) error {
+	var result statusResult
 
+	// names lists the applications and resources that aren't up, for -o name
+	var names []string
 	errList := make([]string, 0)
-	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-	fmt.Fprintf(w, "\nSTATUS CHECK\tREADY")
 
-	// Getting application health check summary
-	apps, err := getApplicationStatus(c)
-	if err != nil {
-		fmt.Fprintf(w, "\napplications\terror")
+	apps, appErr := getApplicationStatus(kbClient)
+	if appErr != nil {
+		result.Errors = append(result.Errors, appErr.Error())
 	} else {
-		appHealthyCount := 0
-		appTotalCount := 0
+		result.Applications = &apps
 		for _, appStatus := range apps.AppStatuses {
-			appTotalCount += len(appStatus.Endpoints)
 			for _, endpoint := range appStatus.Endpoints {
 				if appStatusMap[endpoint.Status] == "down" {
 					errList = append(errList, fmt.Sprintf("* application \"%s\" is %s", appStatus.App, appStatusMap[endpoint.Status]))
-					continue
+					names = appendUniqueName(names, "application/"+appStatus.App)
 				}
-				appHealthyCount++
 			}
 		}
-		fmt.Fprintf(w, "\napplications\t%d/%d", appHealthyCount, appTotalCount)
 	}
 
-	// Getting resource health check summary
-	resources, err := getResourceStatus(c)
-	if err != nil {
-		fmt.Fprintf(w, "\nresources\terror")
+	resources, resourceErr := getResourceStatus(kbClient)
+	if resourceErr != nil {
+		result.Errors = append(result.Errors, resourceErr.Error())
 	} else {
-		resourceHealthyCount := 0
+		result.Resources = resources
 		for _, r := range resources {
 			if r.Status != "up" {
 				errList = append(errList, fmt.Sprintf("* resource \"%s\" is %s", r.Name, r.Status))
-				continue
+				names = appendUniqueName(names, "resource/"+r.Name)
 			}
-			resourceHealthyCount++
 		}
-		fmt.Fprintf(w, "\nresources\t%d/%d", resourceHealthyCount, len(resources))
 	}
 
-	fmt.Fprintln(w)
-	// Printing all health check errors
-	if len(errList) > 0 {
-		fmt.Fprintf(w, "\nSTATUS DETAILS\n")
-		for _, e := range errList {
-			fmt.Fprintf(w, "%s\n", e)
+	return p.Print(os.Stdout, result, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		fmt.Fprintf(w, "\nSTATUS CHECK\tREADY")
+
+		// Getting application health check summary
+		if appErr != nil {
+			fmt.Fprintf(w, "\napplications\terror")
+		} else {
+			appHealthyCount := 0
+			appTotalCount := 0
+			for _, appStatus := range apps.AppStatuses {
+				appTotalCount += len(appStatus.Endpoints)
+				for _, endpoint := range appStatus.Endpoints {
+					if appStatusMap[endpoint.Status] != "down" {
+						appHealthyCount++
+					}
+				}
+			}
+			fmt.Fprintf(w, "\napplications\t%d/%d", appHealthyCount, appTotalCount)
+		}
+
+		// Getting resource health check summary
+		if resourceErr != nil {
+			fmt.Fprintf(w, "\nresources\terror")
+		} else {
+			resourceHealthyCount := 0
+			for _, r := range resources {
+				if r.Status == "up" {
+					resourceHealthyCount++
+				}
+			}
+			fmt.Fprintf(w, "\nresources\t%d/%d", resourceHealthyCount, len(resources))
+		}
+
+		fmt.Fprintln(w)
+		// Printing all health check errors
+		if len(errList) > 0 {
+			fmt.Fprintf(w, "\nSTATUS DETAILS\n")
+			for _, e := range errList {
+				fmt.Fprintf(w, "%s\n", e)
+			}
+		}
+		return w.Flush()
+	})
+}
+
+func appendUniqueName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
 		}
 	}
-	w.Flush()
-	return nil
+	return append(names, name)
 }
 
 func getHealthStatus(kbClient managers.KBClient, endpoint string) ([]byte, error) {
@@ -204,41 +279,63 @@ func getResourceStatus(kbClient managers.KBClient) ([]managers.ResourceStatus, e
 	return data, nil
 }
 
-func printApplicationStatus(c managers.KBClient) error {
+func printApplicationStatus(c managers.KBClient, p *printer.Printer) error {
 	data, err := getApplicationStatus(c)
 	if err != nil {
 		return errors.Wrap(err, "Failed to print application status results")
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 5, 3, ' ', 0)
-	fmt.Fprintf(w, "\nAPPLICATION\tENDPOINT\tSTATUS\tAGE\n")
-
 	sort.Slice(data.AppStatuses, func(i, j int) bool {
 		return data.AppStatuses[i].App < data.AppStatuses[j].App
 	})
+
+	names := make([]string, 0, len(data.AppStatuses))
 	for _, appStatus := range data.AppStatuses {
-		for _, endpoint := range appStatus.Endpoints {
-			age := time.Now().Sub(appStatus.LastUpdated).Round(time.Second)
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", appStatus.App, endpoint.Endpoint, appStatusMap[endpoint.Status], age)
-		}
+		names = append(names, appStatus.App)
 	}
-	w.Flush()
-	return nil
+
+	// Application statuses have no more columns to show with -o wide
+	return p.Print(os.Stdout, data, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 5, 3, ' ', 0)
+		fmt.Fprintf(w, "\nAPPLICATION\tENDPOINT\tSTATUS\tAGE\n")
+
+		for _, appStatus := range data.AppStatuses {
+			for _, endpoint := range appStatus.Endpoints {
+				age := time.Now().Sub(appStatus.LastUpdated).Round(time.Second)
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", appStatus.App, endpoint.Endpoint, appStatusMap[endpoint.Status], age)
+			}
+		}
+		return w.Flush()
+	})
 }
 
-func printResourceStatus(c managers.KBClient, resources []managers.ResourceStatus) error {
+func printResourceStatus(p *printer.Printer, resources []managers.ResourceStatus) error {
 	resources = getUniqueResources(resources)
 	resources = sortResourceStatus(resources)
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 3, 3, ' ', 0)
-	fmt.Fprintf(w, "\nCATEGORY\tSERVICE\tRESOURCE\tTYPE\tSTATUS\tERRORS\tAGE\n")
-
+	names := make([]string, 0, len(resources))
 	for _, r := range resources {
-		age := time.Now().Sub(r.LastUpdated).Round(time.Second)
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Category, r.Service, r.Name, r.Type, r.Status, r.Error, age)
+		names = append(names, r.Name)
 	}
-	w.Flush()
-	return nil
+
+	return p.Print(os.Stdout, resources, names, func(out io.Writer, wide bool) error {
+		w := tabwriter.NewWriter(out, 1, 3, 3, ' ', 0)
+		if wide {
+			fmt.Fprintf(w, "\nCATEGORY\tSERVICE\tRESOURCE\tTYPE\tSTATUS\tERRORS\tAGE\tLAST UPDATED\n")
+		} else {
+			fmt.Fprintf(w, "\nCATEGORY\tSERVICE\tRESOURCE\tTYPE\tSTATUS\tERRORS\tAGE\n")
+		}
+
+		for _, r := range resources {
+			age := time.Now().Sub(r.LastUpdated).Round(time.Second)
+			if wide {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Category, r.Service, r.Name, r.Type, r.Status, r.Error, age, r.LastUpdated.UTC().Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Category, r.Service, r.Name, r.Type, r.Status, r.Error, age)
+			}
+		}
+		return w.Flush()
+	})
 }
 
 func sortResourceStatus(resources []managers.ResourceStatus) []managers.ResourceStatus {
@@ -36,9 +36,21 @@ var uninstallCmd = &cobra.Command{
 	},
 }
 
+var (
+	unregister bool
+)
+
 func init() {
 	uninstallCmd.Flags().BoolVarP(&force, "force", "f", false, "Whether to force uninstall")
 	uninstallCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+
+	uninstallManifestCmd.Flags().BoolVarP(&force, "force", "f", false, "continue uninstalling when a delete fails")
+	uninstallManifestCmd.Flags().IntVarP(&timeoutSeconds, "timeout", "t", 90, "timeout in seconds")
+	uninstallManifestCmd.Flags().BoolVarP(&showLogs, "show-logs", "l", false, "show delete logs")
+	uninstallManifestCmd.Flags().BoolVarP(&unregister, "unregister", "", false, "unregister the applications of the uninstalled installs")
+	addOverlayFlag(uninstallManifestCmd)
+
+	uninstallCmd.AddCommand(uninstallManifestCmd)
 	rootCmd.AddCommand(uninstallCmd)
 }
 
@@ -51,7 +63,7 @@ func uninstall(installs []string, forceUninstall bool) error {
 	for _, installName := range installs {
 		installRef := managers.InstallReference{
 			Name:      installName,
-			Namespace: defaultNamespace,
+			Namespace: namespace,
 		}
 
 		// Run the deploy container with action=delete
@@ -75,3 +87,47 @@ func uninstall(installs []string, forceUninstall bool) error {
 
 	return nil
 }
+
+var uninstallManifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Remove all installs of a manifest in reverse dependency order",
+	Long:  "Remove all installs of a manifest in reverse dependency order",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return uninstallManifest(args)
+	},
+}
+
+func uninstallManifest(manifests []string) error {
+	c := setup()
+
+	ctx := context.Background()
+	manifestMgr := managers.NewManifestManager(c)
+
+	overlays, err := loadOverlays(overlayFiles)
+	if err != nil {
+		return err
+	}
+
+	for _, manifest := range manifests {
+		manifestRef := managers.ManifestReference{
+			Name:      manifest,
+			Namespace: namespace,
+			Overlays:  overlays,
+		}
+
+		uninstallOpts := managers.ManifestUninstallOpts{
+			ShowLogs:   showLogs,
+			Timeout:    time.Duration(timeoutSeconds) * time.Second,
+			Force:      force,
+			Unregister: unregister,
+		}
+		results, err := manifestMgr.Uninstall(ctx, manifestRef, uninstallOpts)
+		printInstallResults(results)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't uninstall manifest '%s'", manifest)
+		}
+	}
+
+	return nil
+}
@@ -23,5 +23,14 @@ var (
 	showLogs       bool
 	timeoutSeconds int
 
-	skipSmoketests bool
+	skipSmoketests  bool
+	parallelism     int
+	continueOnError bool
+	resume          bool
+
+	overlayFiles []string
+	lockFilename string
+
+	onlyInstalls []string
+	withDeps     bool
 )
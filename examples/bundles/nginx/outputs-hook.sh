@@ -0,0 +1,8 @@
+#!/bin/bash
+set -euo pipefail
+
+k8s_namespace=$(jq -r .namespace < $CONFIG_JSON)
+K8S_PORT=$(jq -r .port < $CONFIG_JSON)
+K8S_RESOURCE_SUFFIX=$(jq -r 'select(.suffix != null and .suffix != "") | "-" + .suffix' < $CONFIG_JSON)
+
+jq -n --arg endpoint "http://nginx-deployment${K8S_RESOURCE_SUFFIX}.${k8s_namespace}:${K8S_PORT}" '{endpoint: $endpoint}'
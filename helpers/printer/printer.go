@@ -0,0 +1,135 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package printer writes the results of list and describe commands as text tables or in a structured format
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FormatText is the default, human-readable output
+	FormatText = ""
+	FormatWide = "wide"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatName = "name"
+
+	jsonPathPrefix = "jsonpath="
+)
+
+// Formats lists the accepted output formats, for flag help
+const Formats = "json, yaml, wide, name or jsonpath=<template>"
+
+// TextFunc writes the human-readable form of a result. wide is set for -o wide, which adds columns where a command has
+// more to show.
+type TextFunc func(w io.Writer, wide bool) error
+
+// Printer writes results in the format chosen with -o
+type Printer struct {
+	format   string
+	jsonPath *jsonpath.JSONPath
+}
+
+// New returns a printer for an output format. jsonpath templates are parsed up front so that mistakes are reported
+// before any work is done.
+func New(format string) (*Printer, error) {
+	p := &Printer{format: format}
+
+	switch {
+	case format == FormatText, format == FormatWide, format == FormatJSON, format == FormatYAML, format == FormatName:
+	case strings.HasPrefix(format, jsonPathPrefix):
+		p.jsonPath = jsonpath.New("output").AllowMissingKeys(true)
+		err := p.jsonPath.Parse(strings.TrimPrefix(format, jsonPathPrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse jsonpath template")
+		}
+	default:
+		return nil, fmt.Errorf("unknown output format '%s'; expected %s", format, Formats)
+	}
+
+	return p, nil
+}
+
+// IsText returns true if the printer writes human-readable text
+func (p *Printer) IsText() bool {
+	return p.format == FormatText || p.format == FormatWide
+}
+
+// Print writes a result. obj is encoded for json, yaml and jsonpath, using its json field names. names are written one
+// per line for -o name. Otherwise text writes the result.
+func (p *Printer) Print(w io.Writer, obj interface{}, names []string, text TextFunc) error {
+	switch {
+	case p.format == FormatJSON:
+		b, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "couldn't encode json")
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+
+	case p.format == FormatYAML:
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return errors.Wrap(err, "couldn't encode yaml")
+		}
+		_, err = w.Write(b)
+		return err
+
+	case p.format == FormatName:
+		for _, name := range names {
+			_, err := fmt.Fprintln(w, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case p.jsonPath != nil:
+		// Round trip through json so the template sees json field names, as kubectl does
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return errors.Wrap(err, "couldn't encode json")
+		}
+		var data interface{}
+		err = json.Unmarshal(b, &data)
+		if err != nil {
+			return errors.Wrap(err, "couldn't decode json")
+		}
+
+		var buf bytes.Buffer
+		err = p.jsonPath.Execute(&buf, data)
+		if err != nil {
+			return errors.Wrap(err, "couldn't execute jsonpath template")
+		}
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+
+	return text(w, p.format == FormatWide)
+}
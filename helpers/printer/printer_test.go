@@ -0,0 +1,82 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func TestPrint(t *testing.T) {
+	items := []item{{Name: "a", Value: 1}, {Name: "b", Value: 2}}
+	names := []string{"a", "b"}
+	text := func(w io.Writer, wide bool) error {
+		for _, i := range items {
+			if wide {
+				fmt.Fprintf(w, "%s %d\n", i.Name, i.Value)
+			} else {
+				fmt.Fprintf(w, "%s\n", i.Name)
+			}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{FormatText, "a\nb\n"},
+		{FormatWide, "a 1\nb 2\n"},
+		{FormatName, "a\nb\n"},
+		{FormatJSON, "[\n  {\n    \"name\": \"a\",\n    \"value\": 1\n  },\n  {\n    \"name\": \"b\",\n    \"value\": 2\n  }\n]\n"},
+		{FormatYAML, "- name: a\n  value: 1\n- name: b\n  value: 2\n"},
+		{"jsonpath={[*].value}", "1 2\n"},
+		{"jsonpath={[0].missing}", ""},
+	}
+
+	for _, test := range tests {
+		p, err := New(test.format)
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+
+		var buf bytes.Buffer
+		err = p.Print(&buf, items, names, text)
+		if err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.format, test.expected, buf.String())
+		}
+	}
+}
+
+func TestNewInvalidFormat(t *testing.T) {
+	for _, format := range []string{"xml", "jsonpath={.items["} {
+		_, err := New(format)
+		if err == nil {
+			t.Errorf("expected an error for format '%s'", format)
+		}
+	}
+}
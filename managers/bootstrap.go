@@ -19,6 +19,7 @@ package managers
 import (
 	"context"
 	"embed"
+	"fmt"
 	"path/filepath"
 
 	"github.com/pkg/errors"
@@ -58,7 +59,8 @@ func NewBootstrapManager(c KBClient) *BootstrapManager {
 	}
 }
 
-func (sm *BootstrapManager) DeployAll(ctx context.Context, skipCRDs, skipBindings, skipFlavors, skipAirgap bool, provider string, gcrInfo GCRInfo) error {
+// DeployAll deploys the CRDs, and the bindings, flavors and registry proxy that kb needs to deploy installs in namespace
+func (sm *BootstrapManager) DeployAll(ctx context.Context, namespace string, skipCRDs, skipBindings, skipFlavors, skipAirgap bool, provider string, gcrInfo GCRInfo) error {
 	registryMgr := NewRegistryManager(sm.c)
 
 	if !skipCRDs {
@@ -69,14 +71,14 @@ func (sm *BootstrapManager) DeployAll(ctx context.Context, skipCRDs, skipBinding
 	}
 
 	if !skipBindings {
-		err := sm.DeployBindings(ctx)
+		err := sm.DeployBindings(ctx, namespace)
 		if err != nil {
 			return errors.Wrap(err, "couldn't deploy bindings")
 		}
 	}
 
 	if !skipFlavors {
-		err := sm.DeployFlavors(ctx)
+		err := sm.DeployFlavors(ctx, namespace)
 		if err != nil {
 			return errors.Wrap(err, "couldn't deploy flavors")
 		}
@@ -126,7 +128,9 @@ func (sm *BootstrapManager) DeployCRDs(ctx context.Context) error {
 	return nil
 }
 
-func (sm *BootstrapManager) DeployBindings(ctx context.Context) error {
+// DeployBindings grants the deploy jobs in namespace the permissions they need. Bindings for namespaces other than the
+// default one are suffixed with the namespace.
+func (sm *BootstrapManager) DeployBindings(ctx context.Context, namespace string) error {
 	entries, err := embeddedResources.ReadDir(bindingDir)
 	if err != nil {
 		return errors.Wrap(err, "couldn't read embeded files")
@@ -150,7 +154,24 @@ func (sm *BootstrapManager) DeployBindings(ctx context.Context) error {
 		u := &unstructured.Unstructured{}
 		u.SetUnstructuredContent(m)
 
-		log.WithFields(log.Fields{"name": entry.Name()}).Info("Applying binding")
+		if namespace != defaultNamespace {
+			u.SetName(fmt.Sprintf("%s-%s", u.GetName(), namespace))
+		}
+		subjects, _, err := unstructured.NestedSlice(u.Object, "subjects")
+		if err != nil {
+			return errors.Wrapf(err, "couldn't read subjects in file '%s'", entry.Name())
+		}
+		for _, subject := range subjects {
+			if subject, ok := subject.(map[string]interface{}); ok && subject["kind"] == "ServiceAccount" {
+				subject["namespace"] = namespace
+			}
+		}
+		err = unstructured.SetNestedSlice(u.Object, subjects, "subjects")
+		if err != nil {
+			return errors.Wrapf(err, "couldn't set subjects in file '%s'", entry.Name())
+		}
+
+		log.WithFields(log.Fields{"name": u.GetName()}).Info("Applying binding")
 		err = sm.resourceMgr.Apply(ctx, u)
 		if err != nil {
 			return errors.Wrapf(err, "couldn't apply yaml from file '%s'", entry.Name())
@@ -160,7 +181,8 @@ func (sm *BootstrapManager) DeployBindings(ctx context.Context) error {
 	return nil
 }
 
-func (sm *BootstrapManager) DeployFlavors(ctx context.Context) error {
+// DeployFlavors deploys the built-in flavors in namespace
+func (sm *BootstrapManager) DeployFlavors(ctx context.Context, namespace string) error {
 	entries, err := embeddedResources.ReadDir(flavorDir)
 	if err != nil {
 		return errors.Wrap(err, "couldn't read embedded flavor files")
@@ -184,6 +206,8 @@ func (sm *BootstrapManager) DeployFlavors(ctx context.Context) error {
 		u := &unstructured.Unstructured{}
 		u.SetUnstructuredContent(m)
 
+		u.SetNamespace(namespace)
+
 		log.WithFields(log.Fields{"name": entry.Name()}).Info("Applying Flavor")
 		err = sm.resourceMgr.Apply(ctx, u)
 		if err != nil {
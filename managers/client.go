@@ -28,4 +28,7 @@ type KBClient struct {
 	client.Client
 	kubernetes.Interface
 	RestConfig *rest.Config
+
+	// KeyProvider encrypts generated secrets at rest. Secrets are stored unencrypted when nil.
+	KeyProvider KeyProvider
 }
@@ -0,0 +1,94 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ClusterResult is the outcome of deploying to the cluster of a single kubeconfig context
+type ClusterResult struct {
+	Context  string
+	Results  []InstallResult
+	Duration time.Duration
+	Err      error
+}
+
+// ClusterDeployFunc deploys to the cluster of a kubeconfig context, writing its progress to out
+type ClusterDeployFunc func(ctx context.Context, kubeContext string, out io.Writer) ([]InstallResult, error)
+
+// DeployClusters runs deploy for each kubeconfig context, up to parallelism at once, and returns the outcome of each in
+// the order of contexts. The output of each cluster is written to out, prefixed with its context. A failure on one
+// cluster doesn't stop the others.
+func DeployClusters(ctx context.Context, contexts []string, parallelism int, out io.Writer, deploy ClusterDeployFunc) []ClusterResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var outMu sync.Mutex
+	var wg sync.WaitGroup
+
+	clusters := make([]ClusterResult, len(contexts))
+	for i, kubeContext := range contexts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, kubeContext string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			pw := NewPrefixWriter(out, &outMu, fmt.Sprintf("[%s] ", kubeContext))
+			defer pw.Flush()
+
+			start := time.Now()
+			clusters[i] = ClusterResult{Context: kubeContext}
+			clusters[i].Results, clusters[i].Err = deploy(ctx, kubeContext, pw)
+			clusters[i].Duration = time.Since(start)
+		}(i, kubeContext)
+	}
+	wg.Wait()
+
+	return clusters
+}
+
+// WriteClusterResults writes a table with the outcome of deploying to each cluster. Installs that were unchanged count
+// as succeeded.
+func WriteClusterResults(w io.Writer, clusters []ClusterResult) error {
+	tw := tabwriter.NewWriter(w, 1, 3, 3, ' ', 0)
+	fmt.Fprintf(tw, "CONTEXT\tRESULT\tSUCCEEDED\tDURATION\tERROR\n")
+	for _, cluster := range clusters {
+		result := ResultSucceeded
+		errMsg := ""
+		if cluster.Err != nil {
+			result = ResultFailed
+			errMsg = cluster.Err.Error()
+		}
+
+		succeeded := 0
+		for _, r := range cluster.Results {
+			if r.Result == ResultSucceeded || r.Result == ResultUnchanged {
+				succeeded++
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%v\t%s\n", cluster.Context, result, succeeded, len(cluster.Results), cluster.Duration.Round(time.Second), errMsg)
+	}
+	return tw.Flush()
+}
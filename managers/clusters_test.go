@@ -0,0 +1,162 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestDeployClusters(t *testing.T) {
+	tests := []struct {
+		name        string
+		contexts    []string
+		parallelism int
+		failing     map[string]bool
+	}{
+		{name: "sequential", contexts: []string{"a", "b", "c"}, parallelism: 1},
+		{name: "parallel", contexts: []string{"a", "b", "c", "d"}, parallelism: 2},
+		{name: "parallelism above cluster count", contexts: []string{"a", "b"}, parallelism: 5},
+		{name: "parallelism below one runs sequentially", contexts: []string{"a", "b"}, parallelism: 0},
+		{name: "failure doesn't stop other clusters", contexts: []string{"a", "b", "c"}, parallelism: 2, failing: map[string]bool{"b": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning int32
+			deploy := func(ctx context.Context, kubeContext string, out io.Writer) ([]InstallResult, error) {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+
+				fmt.Fprintf(out, "deploying\ndone")
+				if tt.failing[kubeContext] {
+					return []InstallResult{{Name: "web", Result: ResultFailed}}, errors.New("boom")
+				}
+				return []InstallResult{{Name: "web", Result: ResultSucceeded}, {Name: "db", Result: ResultUnchanged}}, nil
+			}
+
+			var out bytes.Buffer
+			clusters := DeployClusters(context.Background(), tt.contexts, tt.parallelism, &out, deploy)
+
+			limit := tt.parallelism
+			if limit < 1 {
+				limit = 1
+			}
+			if int(maxRunning) > limit {
+				t.Errorf("expected at most %d clusters at once, got %d", limit, maxRunning)
+			}
+
+			if len(clusters) != len(tt.contexts) {
+				t.Fatalf("expected %d results, got %d", len(tt.contexts), len(clusters))
+			}
+			for i, kubeContext := range tt.contexts {
+				cluster := clusters[i]
+				if cluster.Context != kubeContext {
+					t.Errorf("expected result %d for context %s, got %s", i, kubeContext, cluster.Context)
+				}
+				if tt.failing[kubeContext] {
+					if cluster.Err == nil {
+						t.Errorf("expected an error for context %s", kubeContext)
+					}
+				} else if cluster.Err != nil {
+					t.Errorf("unexpected error for context %s: %v", kubeContext, cluster.Err)
+				}
+				if len(cluster.Results) == 0 {
+					t.Errorf("expected install results for context %s", kubeContext)
+				}
+
+				// An unterminated last line is flushed when the cluster finishes
+				for _, line := range []string{"deploying", "done"} {
+					expected := fmt.Sprintf("[%s] %s\n", kubeContext, line)
+					if !strings.Contains(out.String(), expected) {
+						t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteClusterResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		cluster  ClusterResult
+		expected []string
+	}{
+		{
+			name: "succeeded",
+			cluster: ClusterResult{
+				Context:  "prod",
+				Results:  []InstallResult{{Result: ResultSucceeded}, {Result: ResultUnchanged}},
+				Duration: 1600 * time.Millisecond,
+			},
+			expected: []string{"prod", ResultSucceeded, "2/2", "2s"},
+		},
+		{
+			name: "failed",
+			cluster: ClusterResult{
+				Context: "staging",
+				Results: []InstallResult{{Result: ResultSucceeded}, {Result: ResultFailed}, {Result: ResultSkipped}},
+				Err:     errors.New("couldn't deploy manifest 'app'"),
+			},
+			expected: []string{"staging", ResultFailed, "1/3", "0s", "couldn't deploy manifest 'app'"},
+		},
+		{
+			name:     "unreachable",
+			cluster:  ClusterResult{Context: "dev", Err: errors.New("no such context")},
+			expected: []string{"dev", ResultFailed, "0/0", "0s", "no such context"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := WriteClusterResults(&out, []ClusterResult{tt.cluster})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected a header and one row, got:\n%s", out.String())
+			}
+			if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "CONTEXT RESULT SUCCEEDED DURATION ERROR" {
+				t.Errorf("unexpected header %q", lines[0])
+			}
+			for _, field := range tt.expected {
+				if !strings.Contains(lines[1], field) {
+					t.Errorf("expected row to contain %q, got %q", field, lines[1])
+				}
+			}
+		})
+	}
+}
@@ -19,6 +19,9 @@ package managers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -28,12 +31,14 @@ import (
 type ConfigManager struct {
 	kbClient    KBClient
 	resourceMgr *ResourceManager
+	deployMgr   *DeployManager
 }
 
 func NewConfigManager(kbClient KBClient) *ConfigManager {
 	return &ConfigManager{
 		kbClient:    kbClient,
 		resourceMgr: NewResourceManager(kbClient),
+		deployMgr:   NewDeployManager(kbClient),
 	}
 }
 
@@ -60,7 +65,7 @@ func (cm *ConfigManager) Get(ctx context.Context, installRef InstallReference, k
 		return "", errors.Wrapf(err, "couldn't get application %q", appName)
 	}
 
-	pm := NewParameterManager(cm.kbClient, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+	pm := NewParameterManager(cm.kbClient, installRef.Namespace, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
 	m, err := pm.GetMergedMap()
 	if err != nil {
 		return "", errors.Wrap(err, "couldn't get merged map")
@@ -82,8 +87,8 @@ func (cm *ConfigManager) List(ctx context.Context, installRef InstallReference)
 		return nil, errors.Wrapf(err, "couldn't get application %q", appName)
 	}
 
-	pm := NewParameterManager(cm.kbClient, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
-	m, err := pm.GetMergedMap()
+	pm := NewParameterManager(cm.kbClient, installRef.Namespace, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+	m, err := pm.GetDisplayMap()
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't get merged map")
 	}
@@ -91,47 +96,187 @@ func (cm *ConfigManager) List(ctx context.Context, installRef InstallReference)
 }
 
 func (cm *ConfigManager) Set(ctx context.Context, installRef InstallReference, key, value string) error {
-	// reject unknown configs for a given bundle
-	configMap, err := cm.List(ctx, installRef)
-	if err != nil {
-		return errors.Wrap(err, "failed to get config map")
+	_, err := cm.SetValues(ctx, installRef, map[string]string{key: value}, true)
+	return err
+}
+
+// SetValues overrides parameters of an install. The values are validated against the Application's definitions, and
+// nothing is changed unless apply is set. Returns the changes of the merged parameters.
+func (cm *ConfigManager) SetValues(ctx context.Context, installRef InstallReference, values map[string]string, apply bool) ([]ConfigChange, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
-	if _, isPresent := configMap[key]; !isPresent {
-		return fmt.Errorf("unknown config '%s' for install '%s'", key, installRef.Name)
+	sort.Strings(keys)
+
+	bundle := v1alpha1.BundleSpec{Name: installRef.Name}
+	for _, key := range keys {
+		log.WithFields(log.Fields{"name": key, "value": values[key]}).Debug("Overriding parameter")
+		bundle.Parameters = append(bundle.Parameters, v1alpha1.ParameterSpec{Name: key, Value: values[key]})
 	}
 
-	var originalInstall v1alpha1.Install
-	err = cm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &originalInstall)
+	return cm.Import(ctx, installRef.Namespace, ConfigExport{Bundles: []v1alpha1.BundleSpec{bundle}}, apply)
+}
+
+// ConfigApplyOpts controls how changed config values are deployed
+type ConfigApplyOpts struct {
+	// WithDependents also deploys the installs requiring the install, if their inputs changed
+	WithDependents bool
+
+	Timeout  time.Duration
+	ShowLogs bool
+}
+
+// Apply deploys an install after its config values changed. With WithDependents, the installs that require it,
+// directly or through other installs, are deployed next in dependency order, skipping those whose inputs are the same
+// as before. Returns the names of the deployed installs.
+func (cm *ConfigManager) Apply(ctx context.Context, installRef InstallReference, opts ConfigApplyOpts) ([]string, error) {
+	var dependents []v1alpha1.Install
+	beforeHashes := make(map[string]string)
+	if opts.WithDependents {
+		var err error
+		dependents, err = cm.getDependents(ctx, installRef)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dependent := range dependents {
+			if dependent.Status.LastApplied != nil {
+				beforeHashes[dependent.Name] = dependent.Status.LastApplied.InputsHash
+				continue
+			}
+			hash, err := cm.deployMgr.InputsHash(ctx, InstallReference{Name: dependent.Name, Namespace: dependent.Namespace})
+			if err != nil {
+				return nil, errors.Wrapf(err, "couldn't get inputs of %q", dependent.Name)
+			}
+			beforeHashes[dependent.Name] = hash
+		}
+	}
+
+	var deployed []string
+	err := cm.deployAndRecord(ctx, installRef, opts)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't find install %q", installRef.Name)
+		return deployed, err
 	}
+	deployed = append(deployed, installRef.Name)
 
-	newInstall := originalInstall.DeepCopy()
+	for _, dependent := range dependents {
+		dependentRef := InstallReference{Name: dependent.Name, Namespace: dependent.Namespace}
+		hash, err := cm.deployMgr.InputsHash(ctx, dependentRef)
+		if err != nil {
+			return deployed, errors.Wrapf(err, "couldn't get inputs of %q", dependent.Name)
+		}
+		if hash == beforeHashes[dependent.Name] {
+			log.WithField("install", dependent.Name).Info("Inputs unchanged, skipping")
+			continue
+		}
 
-	found := false
-	for i, parameter := range newInstall.Spec.Parameters {
-		if parameter.Name == key {
-			newInstall.Spec.Parameters[i].Value = value
-			found = true
-			log.WithFields(log.Fields{"name": key, "value": value}).Debug("Overriding existing parameter")
-			break
+		err = cm.deployAndRecord(ctx, dependentRef, opts)
+		if err != nil {
+			return deployed, err
 		}
+		deployed = append(deployed, dependent.Name)
 	}
 
-	if !found {
-		newInstall.Spec.Parameters = append(newInstall.Spec.Parameters, v1alpha1.ParameterSpec{
-			Name:  key,
-			Value: value,
-		})
-		log.WithFields(log.Fields{"name": key, "value": value}).Debug("Adding new parameter")
+	return deployed, nil
+}
+
+func (cm *ConfigManager) deployAndRecord(ctx context.Context, installRef InstallReference, opts ConfigApplyOpts) error {
+	deployOpts := DeployOpts{
+		Action:  ActionApplyOutputs,
+		Timeout: opts.Timeout,
+	}
+	err := cm.deployMgr.Deploy(ctx, installRef, deployOpts, opts.ShowLogs)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't execute deploy for %q", installRef.Name)
 	}
 
-	err = cm.resourceMgr.Patch(ctx, newInstall, &originalInstall)
+	hash, err := cm.deployMgr.InputsHash(ctx, installRef)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't patch install %q", installRef.Name)
+		return errors.Wrapf(err, "couldn't get inputs of %q", installRef.Name)
 	}
 
-	return nil
+	var install v1alpha1.Install
+	err = cm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &install)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get install %q", installRef.Name)
+	}
+	var manifestGeneration int64
+	if install.Status.LastApplied != nil {
+		manifestGeneration = install.Status.LastApplied.ManifestGeneration
+	}
+	return cm.deployMgr.RecordApplied(ctx, installRef, manifestGeneration, hash)
+}
+
+// getDependents returns the installs that require the given install, directly or indirectly, in dependency order
+func (cm *ConfigManager) getDependents(ctx context.Context, installRef InstallReference) ([]v1alpha1.Install, error) {
+	var list v1alpha1.InstallList
+	err := cm.resourceMgr.List(ctx, installRef.Namespace, &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list installs")
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	// requires maps each install to the installs it requires
+	requires := make(map[string][]string)
+	for i := range list.Items {
+		app, err := cm.getApplication(ctx, &list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, require := range app.Spec.Requires {
+			requires[list.Items[i].Name] = append(requires[list.Items[i].Name], getResourceName(require.Name, require.Suffix))
+		}
+	}
+
+	// Find the installs that transitively require the install
+	affected := map[string]bool{installRef.Name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, install := range list.Items {
+			if affected[install.Name] {
+				continue
+			}
+			for _, required := range requires[install.Name] {
+				if affected[required] {
+					affected[install.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	// Order them so each install comes after the affected installs it requires
+	done := map[string]bool{installRef.Name: true}
+	var dependents []v1alpha1.Install
+	for len(done) < len(affected) {
+		progress := false
+		for _, install := range list.Items {
+			if !affected[install.Name] || done[install.Name] {
+				continue
+			}
+			ready := true
+			for _, required := range requires[install.Name] {
+				if affected[required] && !done[required] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				dependents = append(dependents, install)
+				done[install.Name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle between the installs requiring %q", installRef.Name)
+		}
+	}
+
+	return dependents, nil
 }
 
 func (cm *ConfigManager) Remove(ctx context.Context, installRef InstallReference, key string) error {
@@ -144,22 +289,25 @@ func (cm *ConfigManager) Remove(ctx context.Context, installRef InstallReference
 	newInstall := originalInstall.DeepCopy()
 	newInstall.Spec.Parameters = make([]v1alpha1.ParameterSpec, 0)
 
-	secret, err := getGlobalSecret(cm.kbClient)
+	store := newGeneratedSecretStore(cm.kbClient, installRef.Name, installRef.Namespace)
+	secret, err := store.get(ctx)
 	if err != nil {
-		return errors.Wrap(err, "Failed to get global secret")
+		return errors.Wrap(err, "Failed to get generated secrets")
 	}
 
 	for i, parameter := range originalInstall.Spec.Parameters {
 		if parameter.Name != key {
 			newInstall.Spec.Parameters = append(newInstall.Spec.Parameters, originalInstall.Spec.Parameters[i])
 		} else {
-			// if the parameter uses generateSecret, get the default generated secret from global-secrets
-			secretKey := installRef.Name + "." + parameter.Name
-			secretValue, found := secret.Data[secretKey]
+			// if the parameter uses generateSecret, get the default generated secret from the install's generated secrets
+			secretValue, found, err := store.value(ctx, secret, parameter.Name)
+			if err != nil {
+				return err
+			}
 			if found {
 				newInstall.Spec.Parameters = append(newInstall.Spec.Parameters, v1alpha1.ParameterSpec{
 					Name:           parameter.Name,
-					Value:          string(secretValue[:]),
+					Value:          secretValue,
 					GenerateSecret: parameter.GenerateSecret,
 				})
 			} else {
@@ -175,3 +323,219 @@ func (cm *ConfigManager) Remove(ctx context.Context, installRef InstallReference
 
 	return nil
 }
+
+// ConfigExport holds the non-default parameters of several installs. Bundles are shaped like ManifestSpec.Bundles,
+// named after the installs.
+type ConfigExport struct {
+	Bundles []v1alpha1.BundleSpec `json:"bundles"`
+}
+
+// ConfigChange is a change of a parameter's effective value. Values are shown as by kb installs describe.
+type ConfigChange struct {
+	Install   string
+	Parameter string
+	Before    string
+	After     string
+}
+
+// Export returns the non-default parameters of the given installs, or of all installs in the namespace if none are
+// given. Generated and sensitive values are left out. Parameters read from Secrets are only included with
+// includeSecretRefs.
+func (cm *ConfigManager) Export(ctx context.Context, namespace string, installs []string, includeSecretRefs bool) (ConfigExport, error) {
+	var list v1alpha1.InstallList
+	err := cm.resourceMgr.List(ctx, namespace, &list)
+	if err != nil {
+		return ConfigExport{}, errors.Wrap(err, "couldn't list installs")
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+
+	var export ConfigExport
+	found := make(map[string]bool)
+	for _, install := range list.Items {
+		if len(installs) > 0 && !stringSliceContains(installs, install.Name) {
+			continue
+		}
+		found[install.Name] = true
+
+		app, err := cm.getApplication(ctx, &install)
+		if err != nil {
+			return ConfigExport{}, err
+		}
+
+		definitions := make(map[string]v1alpha1.ParameterDefinitionSpec)
+		for _, definition := range app.Spec.ParameterDefinitions {
+			definitions[definition.Name] = definition
+		}
+
+		bundle := v1alpha1.BundleSpec{
+			Name:    install.Name,
+			Version: install.Spec.Version,
+		}
+		for _, parameter := range install.Spec.Parameters {
+			definition := definitions[parameter.Name]
+			switch {
+			case parameter.GenerateSecret.Format != "":
+				continue
+			case parameter.ValueFrom != nil:
+				if isSecretRef(parameter.ValueFrom) && !includeSecretRefs {
+					continue
+				}
+				bundle.Parameters = append(bundle.Parameters, v1alpha1.ParameterSpec{Name: parameter.Name, ValueFrom: parameter.ValueFrom})
+			case definition.Sensitive:
+				log.WithFields(log.Fields{"install": install.Name, "name": parameter.Name}).Warn("Not exporting sensitive parameter")
+			case parameter.Value != definition.Default:
+				bundle.Parameters = append(bundle.Parameters, v1alpha1.ParameterSpec{Name: parameter.Name, Value: parameter.Value})
+			}
+		}
+		export.Bundles = append(export.Bundles, bundle)
+	}
+
+	for _, installName := range installs {
+		if !found[installName] {
+			return ConfigExport{}, fmt.Errorf("install %q not found", installName)
+		}
+	}
+
+	return export, nil
+}
+
+// Import sets the parameters of an export on the installs of the same name. All installs are validated against their
+// Applications' definitions before any is changed, and nothing is changed unless apply is set. Returns the changes.
+func (cm *ConfigManager) Import(ctx context.Context, namespace string, export ConfigExport, apply bool) ([]ConfigChange, error) {
+	type plannedInstall struct {
+		original *v1alpha1.Install
+		updated  *v1alpha1.Install
+	}
+
+	var planned []plannedInstall
+	var changes []ConfigChange
+	var problems []string
+	for _, bundle := range export.Bundles {
+		var install v1alpha1.Install
+		err := cm.resourceMgr.Get(ctx, bundle.Name, namespace, &install)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("install %q: %v", bundle.Name, err))
+			continue
+		}
+
+		app, err := cm.getApplication(ctx, &install)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("install %q: %v", bundle.Name, err))
+			continue
+		}
+		if bundle.Version != "" && bundle.Version != install.Spec.Version {
+			log.WithFields(log.Fields{"install": bundle.Name, "exported": bundle.Version, "installed": install.Spec.Version}).Warn("Importing configuration exported from a different version")
+		}
+
+		updated := install.DeepCopy()
+		unknown := false
+		for _, parameter := range bundle.Parameters {
+			if !hasDefinition(app.Spec.ParameterDefinitions, parameter.Name) {
+				problems = append(problems, fmt.Sprintf("install %q: unknown config '%s'", bundle.Name, parameter.Name))
+				unknown = true
+				continue
+			}
+			updated.Spec.Parameters = setParameter(updated.Spec.Parameters, parameter)
+		}
+		if unknown {
+			continue
+		}
+
+		pm := NewParameterManager(cm.kbClient, install.Namespace, install.Name, app.Spec.ParameterDefinitions, updated.Spec.Parameters)
+		err = pm.Validate()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("install %q: %v", bundle.Name, err))
+			continue
+		}
+
+		installChanges := diffParameters(install.Namespace, install.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters, updated.Spec.Parameters)
+		if len(installChanges) > 0 {
+			changes = append(changes, installChanges...)
+			planned = append(planned, plannedInstall{original: &install, updated: updated})
+		}
+	}
+
+	if len(problems) > 0 {
+		return changes, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	if !apply {
+		return changes, nil
+	}
+
+	for _, p := range planned {
+		err := cm.resourceMgr.Patch(ctx, p.updated, p.original)
+		if err != nil {
+			return changes, errors.Wrapf(err, "couldn't patch install %q", p.original.Name)
+		}
+	}
+
+	return changes, nil
+}
+
+func (cm *ConfigManager) getApplication(ctx context.Context, install *v1alpha1.Install) (*v1alpha1.Application, error) {
+	appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
+	var app v1alpha1.Application
+	err := cm.resourceMgr.Get(ctx, appName, install.Namespace, &app)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get application %q", appName)
+	}
+	return &app, nil
+}
+
+func hasDefinition(definitions []v1alpha1.ParameterDefinitionSpec, name string) bool {
+	for _, definition := range definitions {
+		if definition.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setParameter replaces the value of a parameter, or appends the parameter if it isn't set
+func setParameter(parameters []v1alpha1.ParameterSpec, parameter v1alpha1.ParameterSpec) []v1alpha1.ParameterSpec {
+	for i := range parameters {
+		if parameters[i].Name == parameter.Name {
+			parameters[i].Value = parameter.Value
+			parameters[i].ValueFrom = parameter.ValueFrom
+			parameters[i].GenerateSecret = parameter.GenerateSecret
+			return parameters
+		}
+	}
+	return append(parameters, parameter)
+}
+
+// diffParameters returns the parameters whose effective value differs between two sets of overrides
+func diffParameters(namespace, installName string, definitions []v1alpha1.ParameterDefinitionSpec, before, after []v1alpha1.ParameterSpec) []ConfigChange {
+	beforeDesc := NewParameterManager(KBClient{}, namespace, installName, definitions, before).GetParameterDesc()
+	afterDesc := NewParameterManager(KBClient{}, namespace, installName, definitions, after).GetParameterDesc()
+
+	names := make([]string, 0, len(afterDesc))
+	for name := range afterDesc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []ConfigChange
+	for _, name := range names {
+		b, a := displayParameterValue(beforeDesc[name]), displayParameterValue(afterDesc[name])
+		if b != a || beforeDesc[name].Value != afterDesc[name].Value {
+			changes = append(changes, ConfigChange{
+				Install:   installName,
+				Parameter: name,
+				Before:    b,
+				After:     a,
+			})
+		}
+	}
+	return changes
+}
+
+// displayParameterValue returns a parameter value, masked if the parameter is sensitive
+func displayParameterValue(desc ParameterDesc) string {
+	if desc.Sensitive && desc.Value != "" {
+		return maskedValue
+	}
+	return desc.Value
+}
@@ -0,0 +1,193 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// newConfigTestClient returns a client holding the mail application and an install of it with the given parameters
+func newConfigTestClient(t *testing.T, parameters []v1alpha1.ParameterSpec) client.Client {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:    "mail",
+			Version: "1.0.0",
+			ParameterDefinitions: []v1alpha1.ParameterDefinitionSpec{
+				{Name: "host", Default: "smtp.local"},
+				{Name: "port", Default: "25", Type: v1alpha1.ParameterTypeInt},
+				{Name: "password", Sensitive: true},
+				{Name: "apiKey", GenerateSecret: v1alpha1.GenerateSecret{Format: "hex", Bytes: 16}},
+				{Name: "smtpPassword"},
+				{Name: "licenseKey"},
+			},
+		},
+	}
+	install := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "mail", Version: "1.0.0", Parameters: parameters},
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(app, install).Build()
+}
+
+func TestConfigExportImportRoundTrip(t *testing.T) {
+	secretRef := &v1alpha1.ParameterValueSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "smtp"},
+		Key:                  "password",
+	}}
+	configMapRef := &v1alpha1.ParameterValueSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "license"},
+		Key:                  "key",
+	}}
+	source := newConfigTestClient(t, []v1alpha1.ParameterSpec{
+		{Name: "host", Value: "mail.example.com"},
+		{Name: "port", Value: "25"},
+		{Name: "password", Value: "hunter2"},
+		{Name: "apiKey", GenerateSecret: v1alpha1.GenerateSecret{Format: "uuid"}},
+		{Name: "smtpPassword", ValueFrom: secretRef},
+		{Name: "licenseKey", ValueFrom: configMapRef},
+	})
+
+	tests := []struct {
+		name              string
+		includeSecretRefs bool
+		expected          []v1alpha1.ParameterSpec
+	}{
+		{
+			name: "without secret refs",
+			expected: []v1alpha1.ParameterSpec{
+				{Name: "host", Value: "mail.example.com"},
+				{Name: "licenseKey", ValueFrom: configMapRef},
+			},
+		},
+		{
+			name:              "with secret refs",
+			includeSecretRefs: true,
+			expected: []v1alpha1.ParameterSpec{
+				{Name: "host", Value: "mail.example.com"},
+				{Name: "smtpPassword", ValueFrom: secretRef},
+				{Name: "licenseKey", ValueFrom: configMapRef},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		export, err := NewConfigManager(KBClient{Client: source}).Export(context.Background(), "default", nil, test.includeSecretRefs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(export.Bundles) != 1 || export.Bundles[0].Name != "mail" || export.Bundles[0].Version != "1.0.0" {
+			t.Fatalf("%s: unexpected export %+v", test.name, export)
+		}
+		if !equality.Semantic.DeepEqual(export.Bundles[0].Parameters, test.expected) {
+			t.Errorf("%s: expected exported parameters %+v, got %+v", test.name, test.expected, export.Bundles[0].Parameters)
+		}
+
+		// Import into another cluster through the yaml written by kb config export
+		b, err := yaml.Marshal(export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "hunter2") {
+			t.Errorf("%s: expected the sensitive value to be left out of the export:\n%s", test.name, b)
+		}
+		var imported ConfigExport
+		err = yaml.Unmarshal(b, &imported)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		target := newConfigTestClient(t, nil)
+		cm := NewConfigManager(KBClient{Client: target})
+		changes, err := cm.Import(context.Background(), "default", imported, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != len(test.expected) {
+			t.Errorf("%s: expected %d changes, got %+v", test.name, len(test.expected), changes)
+		}
+		unchanged, err := cm.Export(context.Background(), "default", nil, test.includeSecretRefs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(unchanged.Bundles[0].Parameters) != 0 {
+			t.Errorf("%s: expected a dry run import to leave the install alone, got %+v", test.name, unchanged.Bundles[0].Parameters)
+		}
+
+		_, err = cm.Import(context.Background(), "default", imported, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip, err := cm.Export(context.Background(), "default", nil, test.includeSecretRefs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equality.Semantic.DeepEqual(roundTrip, export) {
+			t.Errorf("%s: expected the imported config to export as %+v, got %+v", test.name, export, roundTrip)
+		}
+	}
+}
+
+func TestConfigImportRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters []v1alpha1.ParameterSpec
+		problem    string
+	}{
+		{name: "unknown parameter", parameters: []v1alpha1.ParameterSpec{{Name: "color", Value: "red"}}, problem: "unknown config 'color'"},
+		{name: "invalid value", parameters: []v1alpha1.ParameterSpec{{Name: "port", Value: "smtp"}}, problem: "parameter 'port'"},
+	}
+
+	for _, test := range tests {
+		c := newConfigTestClient(t, nil)
+		cm := NewConfigManager(KBClient{Client: c})
+		export := ConfigExport{Bundles: []v1alpha1.BundleSpec{
+			{Name: "mail", Parameters: append([]v1alpha1.ParameterSpec{{Name: "host", Value: "mail.example.com"}}, test.parameters...)},
+		}}
+
+		_, err := cm.Import(context.Background(), "default", export, true)
+		if err == nil || !strings.Contains(err.Error(), test.problem) {
+			t.Errorf("%s: expected an error about %s, got %v", test.name, test.problem, err)
+		}
+
+		var install v1alpha1.Install
+		err = c.Get(context.Background(), client.ObjectKey{Name: "mail", Namespace: "default"}, &install)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(install.Spec.Parameters) != 0 {
+			t.Errorf("%s: expected nothing to be imported, got %+v", test.name, install.Spec.Parameters)
+		}
+	}
+}
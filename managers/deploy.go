@@ -18,20 +18,25 @@ package managers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/avast/retry-go"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/splunk/kube-bundler/api/v1alpha1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
@@ -45,10 +50,14 @@ const (
 	ActionSmoketest    = "smoketest"
 	ActionOutput       = "outputs"
 
+	DefaultRetryBackoff = 10 * time.Second
+
 	ParametersFile = "parameters.json"
+	SecretsFile    = "secrets.json"
 	InstallFile    = "install.json"
 	RequiresFile   = "requires.json"
 	FlavorFile     = "flavor.json"
+	OutputsFile    = "outputs.json"
 )
 
 type DeployInfo struct {
@@ -57,7 +66,9 @@ type DeployInfo struct {
 	Action    string
 	Timeout   time.Duration
 
+	out            io.Writer
 	configMap      string
+	secret         string
 	image          string
 	dockerRegistry string
 	parameters     []v1alpha1.ParameterSpec
@@ -65,11 +76,25 @@ type DeployInfo struct {
 	requires       []v1alpha1.RequiresList
 	installSpec    v1alpha1.InstallSpec
 	flavorSpec     v1alpha1.FlavorSpec
+	deployJob      *v1alpha1.DeployJobSpec
+	outputs        map[string]map[string]interface{}
+
+	// readOnly reads parameters without generating missing secret values
+	readOnly bool
 }
 
 type DeployOpts struct {
 	Action  string
 	Timeout time.Duration
+
+	// Out receives job progress and logs. Defaults to os.Stdout
+	Out io.Writer
+
+	// Retries is the number of times a failed job is rerun
+	Retries int
+
+	// RetryBackoff is the delay before the first retry, doubling for each further retry. Defaults to DefaultRetryBackoff
+	RetryBackoff time.Duration
 }
 
 type DeployManager struct {
@@ -87,35 +112,120 @@ func NewDeployManager(c KBClient) *DeployManager {
 }
 
 func (dm *DeployManager) Deploy(ctx context.Context, installRef InstallReference, deployOpts DeployOpts, showLogs bool) error {
+	deployInfo, err := dm.getDeployInfo(ctx, installRef, deployOpts)
+	if err != nil {
+		return err
+	}
+
+	retries := deployOpts.Retries
+	if retries < 0 {
+		retries = 0
+	}
+	retryBackoff := deployOpts.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = DefaultRetryBackoff
+	}
+
+	err = retry.Do(
+		func() error {
+			return dm.runJob(ctx, deployInfo, installRef, showLogs)
+		},
+		retry.Context(ctx),
+		retry.Attempts(uint(retries)+1),
+		retry.Delay(retryBackoff),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.OnRetry(func(n uint, err error) {
+			log.WithFields(log.Fields{"install": deployInfo.Name, "action": deployInfo.Action, "attempt": n + 1, "err": err}).Warn("Job failed, retrying")
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Previous values of rotated secrets are only kept for one deploy
+	if deployInfo.Action == ActionApply || deployInfo.Action == ActionApplyOutputs {
+		err = clearPreviousSecrets(ctx, dm.kbClient, installRef)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't clear previous secrets for %q", deployInfo.Name)
+		}
+	}
+
+	return nil
+}
+
+// runJob replaces the job for the deploy action, waits for it to finish and then waits for the install's resources
+func (dm *DeployManager) runJob(ctx context.Context, deployInfo DeployInfo, installRef InstallReference, showLogs bool) error {
+	// Delete any existing job
+	err := dm.DeleteJob(ctx, installRef, deployInfo.Action)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't delete job for %q", deployInfo.Name)
+	}
+
+	// Update configmap and secret
+	err = dm.createOrPatchConfigmap(ctx, deployInfo)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't create or update configmap for %q", deployInfo.Name)
+	}
+
+	// Create deploy job
+	err = dm.createJob(ctx, deployInfo)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't create job for %q", deployInfo.Name)
+	}
+
+	// Wait on deploy job
+	err = dm.pollJob(ctx, deployInfo, installRef, showLogs)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't poll job for %q", deployInfo.Name)
+	}
+
+	// Wait on resources
+	if deployInfo.Action != ActionDelete {
+		err = dm.rolloutStatusManager.Wait(ctx, installRef, deployInfo.Timeout)
+		if err != nil {
+			return errors.Wrapf(err, "failed waiting for resources for %q", deployInfo.Name)
+		}
+	}
+
+	return nil
+}
+
+// getDeployInfo assembles everything needed to run a deploy job for the given install
+func (dm *DeployManager) getDeployInfo(ctx context.Context, installRef InstallReference, deployOpts DeployOpts) (DeployInfo, error) {
 	deployInfo := DeployInfo{
 		Name:      installRef.Name,
 		Namespace: installRef.Namespace,
 		Action:    deployOpts.Action,
 		Timeout:   deployOpts.Timeout,
+		out:       deployOpts.Out,
+	}
+	if deployInfo.out == nil {
+		deployInfo.out = os.Stdout
 	}
 
 	var install v1alpha1.Install
 	err := dm.resourceMgr.Get(ctx, deployInfo.Name, deployInfo.Namespace, &install)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get install %q", deployInfo.Name)
+		return DeployInfo{}, errors.Wrapf(err, "couldn't get install %q", deployInfo.Name)
 	}
 
 	var flavor v1alpha1.Flavor
-	err = dm.resourceMgr.Get(ctx, install.Spec.Flavor, "default", &flavor)
+	err = dm.resourceMgr.Get(ctx, install.Spec.Flavor, deployInfo.Namespace, &flavor)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get flavor %q", install.Spec.Flavor)
+		return DeployInfo{}, errors.Wrapf(err, "couldn't get flavor %q", install.Spec.Flavor)
 	}
 
 	appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
 	var app v1alpha1.Application
 	err = dm.resourceMgr.Get(ctx, appName, deployInfo.Namespace, &app)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get Application %q", appName)
+		return DeployInfo{}, errors.Wrapf(err, "couldn't get Application %q", appName)
 	}
 
-	err = dm.validateRequiredParameters(installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+	err = dm.validateRequiredParameters(installRef, app.Spec.ParameterDefinitions, install.Spec.Parameters)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't validate parameters for %q", deployInfo.Name)
+		return DeployInfo{}, errors.Wrapf(err, "couldn't validate parameters for %q", deployInfo.Name)
 	}
 
 	// Use a custom cluster registry, if defined
@@ -123,7 +233,7 @@ func (dm *DeployManager) Deploy(ctx context.Context, installRef InstallReference
 		fullImage := "https://" + app.Spec.DeployImage
 		u, err := url.Parse(fullImage)
 		if err != nil {
-			return errors.Wrapf(err, "couldn't parse docker image URL for deployImage '%s'", app.Spec.DeployImage)
+			return DeployInfo{}, errors.Wrapf(err, "couldn't parse docker image URL for deployImage '%s'", app.Spec.DeployImage)
 		}
 		deployInfo.image = path.Join(install.Spec.DockerRegistry, u.Path)
 		deployInfo.dockerRegistry = install.Spec.DockerRegistry
@@ -142,49 +252,55 @@ func (dm *DeployManager) Deploy(ctx context.Context, installRef InstallReference
 
 	// Populate private deployInfo struct members
 	deployInfo.configMap = getResourceName(deployInfo.Name, "") + "-config"
+	deployInfo.secret = getResourceName(deployInfo.Name, "") + "-secrets"
 	deployInfo.parameters = install.Spec.Parameters
 	deployInfo.definitions = app.Spec.ParameterDefinitions
 	deployInfo.requires = app.Spec.Requires
 	deployInfo.installSpec = install.Spec
 	deployInfo.flavorSpec = flavor.Spec
+	deployInfo.deployJob = mergeDeployJobSpec(app.Spec.DeployJob, install.Spec.DeployJob)
 
-	// Delete any existing job
-	err = dm.DeleteJob(ctx, installRef, deployInfo.Action)
+	deployInfo.outputs, err = dm.getRequiredOutputs(ctx, deployInfo.Namespace, app.Spec.Requires)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't delete job for %q", deployInfo.Name)
+		return DeployInfo{}, errors.Wrapf(err, "couldn't get outputs of required installs for %q", deployInfo.Name)
 	}
 
-	// Update configmap
-	err = dm.createOrPatchConfigmap(ctx, deployInfo)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't create or update configmap for %q", deployInfo.Name)
-	}
+	return deployInfo, nil
+}
 
-	// Create deploy job
-	err = dm.createJob(ctx, deployInfo)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't create job for %q", deployInfo.Name)
-	}
+// getRequiredOutputs returns the outputs saved by each required install, keyed by install name. Installs that haven't
+// saved outputs are left out, and parameter templates that reference them fail.
+func (dm *DeployManager) getRequiredOutputs(ctx context.Context, namespace string, requires []v1alpha1.RequiresList) (map[string]map[string]interface{}, error) {
+	outputs := make(map[string]map[string]interface{})
+	for _, require := range requires {
+		baseName := getResourceName(require.Name, require.Suffix)
 
-	// Wait on deploy job
-	err = dm.pollJob(ctx, deployInfo, installRef, showLogs)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't poll job for %q", deployInfo.Name)
-	}
+		var cm corev1.ConfigMap
+		err := dm.resourceMgr.Get(ctx, baseName+"-config", namespace, &cm)
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get configmap for %q", baseName)
+		}
 
-	// Wait on resources
-	if deployInfo.Action != ActionDelete {
-		err = dm.rolloutStatusManager.Wait(ctx, installRef, deployInfo.Timeout)
+		outputsJson, found := cm.Data[OutputsFile]
+		if !found {
+			continue
+		}
+
+		m := make(map[string]interface{})
+		err = json.Unmarshal([]byte(outputsJson), &m)
 		if err != nil {
-			return errors.Wrapf(err, "failed waiting for resources for %q", deployInfo.Name)
+			return nil, errors.Wrapf(err, "couldn't decode outputs for %q", baseName)
 		}
+		outputs[baseName] = m
 	}
 
-	return nil
+	return outputs, nil
 }
 
-func (dm *DeployManager) validateRequiredParameters(installName string, definitions []v1alpha1.ParameterDefinitionSpec, parameters []v1alpha1.ParameterSpec) error {
-	pm := NewParameterManager(dm.kbClient, installName, definitions, parameters)
+func (dm *DeployManager) validateRequiredParameters(installRef InstallReference, definitions []v1alpha1.ParameterDefinitionSpec, parameters []v1alpha1.ParameterSpec) error {
+	pm := NewParameterManager(dm.kbClient, installRef.Namespace, installRef.Name, definitions, parameters)
 	return pm.Validate()
 }
 
@@ -284,49 +400,215 @@ func (dm *DeployManager) Delete(ctx context.Context, installRef InstallReference
 	return nil
 }
 
+// createOrPatchConfigmap writes the job's non-sensitive configuration to its configmap, and its secret parameters to its
+// secret. The secret is mounted at /config alongside the configmap.
 func (dm *DeployManager) createOrPatchConfigmap(ctx context.Context, deployInfo DeployInfo) error {
-	pm := NewParameterManager(dm.kbClient, deployInfo.Name, deployInfo.definitions, deployInfo.parameters)
-	m, err := pm.GetMergedMap()
+	data, secretData, err := dm.getConfigData(deployInfo, true)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = dm.resourceMgr.CreateOrPatch(ctx, deployInfo.configMap, deployInfo.Namespace, cm, func() error {
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		for key, value := range data {
+			cm.Data[key] = value
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't create or update configmap")
+	}
+
+	secret := &corev1.Secret{}
+	err = dm.resourceMgr.CreateOrPatch(ctx, deployInfo.secret, deployInfo.Namespace, secret, func() error {
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		for key, value := range secretData {
+			secret.Data[key] = []byte(value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't create or update secret")
+	}
+
+	return nil
+}
+
+// getConfigData returns the files given to the deploy job in its configmap and in its secret. With includePrevious, the
+// previous values of rotated secrets are added to the secrets.
+func (dm *DeployManager) getConfigData(deployInfo DeployInfo, includePrevious bool) (map[string]string, map[string]string, error) {
+	pm := NewParameterManager(dm.kbClient, deployInfo.Namespace, deployInfo.Name, deployInfo.definitions, deployInfo.parameters)
+	if deployInfo.readOnly {
+		pm.SetReadOnly()
+	}
+	pm.SetTemplateData(ParameterTemplateData{
+		Suffix:  deployInfo.installSpec.Suffix,
+		Flavor:  deployInfo.flavorSpec,
+		Outputs: deployInfo.outputs,
+	})
+	parameters, secrets, err := pm.GetSplitMaps()
 	if err != nil {
-		return errors.Wrap(err, "couldn't get merged map")
+		return nil, nil, errors.Wrap(err, "couldn't get merged map")
 	}
 
-	parametersJson, err := json.Marshal(m)
+	installSecrets, err := pm.GetSecretsMap(deployInfo.installSpec.Secrets)
 	if err != nil {
-		return errors.Wrap(err, "couldn't encode parameters to json")
+		return nil, nil, errors.Wrap(err, "couldn't get install secrets")
+	}
+	for name, value := range installSecrets {
+		secrets[name] = value
+	}
+
+	if includePrevious {
+		previousSecrets, err := pm.GetPreviousSecrets()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "couldn't get previous values of rotated secrets")
+		}
+		for name, value := range previousSecrets {
+			secrets[name] = value
+		}
 	}
 
-	installJson, err := json.Marshal(deployInfo.installSpec)
+	parametersJson, err := json.Marshal(parameters)
 	if err != nil {
-		return errors.Wrap(err, "couldn't encode install spec to json")
+		return nil, nil, errors.Wrap(err, "couldn't encode parameters to json")
+	}
+
+	secretsJson, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't encode secrets to json")
+	}
+
+	// Keep secret values out of install.json, since it is stored in the configmap
+	installSpec := *deployInfo.installSpec.DeepCopy()
+	installSpec.Secrets = nil
+	for i, parameter := range installSpec.Parameters {
+		if pm.IsSecret(parameter.Name) {
+			installSpec.Parameters[i].Value = ""
+		}
+	}
+
+	installJson, err := json.Marshal(installSpec)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't encode install spec to json")
 	}
 
 	requiresJson, err := json.Marshal(deployInfo.requires)
 	if err != nil {
-		return errors.Wrap(err, "couldn't encode require list to json")
+		return nil, nil, errors.Wrap(err, "couldn't encode require list to json")
 	}
 
 	flavorJson, err := json.Marshal(deployInfo.flavorSpec)
 	if err != nil {
-		return errors.Wrap(err, "couldn't encode flavor spec to json")
+		return nil, nil, errors.Wrap(err, "couldn't encode flavor spec to json")
 	}
 
-	cm := &corev1.ConfigMap{}
-	err = dm.resourceMgr.CreateOrPatch(ctx, deployInfo.configMap, deployInfo.Namespace, cm, func() error {
-		if cm.Data == nil {
-			cm.Data = make(map[string]string)
+	data := map[string]string{
+		ParametersFile: string(parametersJson),
+		InstallFile:    string(installJson),
+		RequiresFile:   string(requiresJson),
+		FlavorFile:     string(flavorJson),
+	}
+	secretData := map[string]string{
+		SecretsFile: string(secretsJson),
+	}
+	return data, secretData, nil
+}
+
+// InputsHash returns a hash of the configuration the deploy job would receive for the given install. Two deploys with
+// the same hash apply the same version with the same parameters and flavor. The inputs of the installs it requires are
+// included, so a change to a required install changes the hash of the installs that require it.
+func (dm *DeployManager) InputsHash(ctx context.Context, installRef InstallReference) (string, error) {
+	return dm.inputsHash(ctx, installRef, make(map[string]bool), false)
+}
+
+// PeekInputsHash returns the same hash as InputsHash without changing the cluster. Missing generated secret values and
+// the CA aren't created, and the hash of an install, or of an install it requires, that has a generated parameter
+// without a value yet fails with errNotGenerated.
+func (dm *DeployManager) PeekInputsHash(ctx context.Context, installRef InstallReference) (string, error) {
+	return dm.inputsHash(ctx, installRef, make(map[string]bool), true)
+}
+
+func (dm *DeployManager) inputsHash(ctx context.Context, installRef InstallReference, visiting map[string]bool, readOnly bool) (string, error) {
+	if visiting[installRef.Name] {
+		return "", fmt.Errorf("dependency cycle through install %q", installRef.Name)
+	}
+	visiting[installRef.Name] = true
+	defer delete(visiting, installRef.Name)
+
+	deployInfo, err := dm.getDeployInfo(ctx, installRef, DeployOpts{})
+	if err != nil {
+		return "", err
+	}
+	deployInfo.readOnly = readOnly
+
+	// Previous values of rotated secrets are only delivered once, so they aren't part of the inputs
+	data, secretData, err := dm.getConfigData(deployInfo, false)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range secretData {
+		data[key] = value
+	}
+
+	// Required installs that don't exist yet are left out, as their outputs are
+	for _, require := range deployInfo.requires {
+		requireRef := InstallReference{Name: getResourceName(require.Name, require.Suffix), Namespace: installRef.Namespace}
+		var install v1alpha1.Install
+		err = dm.resourceMgr.Get(ctx, requireRef.Name, requireRef.Namespace, &install)
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			continue
+		} else if err != nil {
+			return "", errors.Wrapf(err, "couldn't get required install %q", requireRef.Name)
 		}
-		cm.Data[ParametersFile] = string(parametersJson)
-		cm.Data[InstallFile] = string(installJson)
-		cm.Data[RequiresFile] = string(requiresJson)
-		cm.Data[FlavorFile] = string(flavorJson)
 
-		return nil
-	})
+		requireHash, err := dm.inputsHash(ctx, requireRef, visiting, readOnly)
+		if err != nil {
+			return "", errors.Wrapf(err, "couldn't hash inputs of required install %q", requireRef.Name)
+		}
+		data["requires/"+requireRef.Name] = requireHash
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s\x00%s\x00", key, data[key])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RecordApplied stores the inputs of a successful deploy in the install's status
+func (dm *DeployManager) RecordApplied(ctx context.Context, installRef InstallReference, manifestGeneration int64, inputsHash string) error {
+	var install v1alpha1.Install
+	err := dm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &install)
 	if err != nil {
-		return errors.Wrap(err, "couldn't create or update configmap")
+		return errors.Wrapf(err, "couldn't get install %q", installRef.Name)
+	}
+
+	newInstall := install.DeepCopy()
+	newInstall.Status.LastApplied = &v1alpha1.LastAppliedStatus{
+		ManifestGeneration: manifestGeneration,
+		Version:            install.Spec.Version,
+		InputsHash:         inputsHash,
+		Time:               metav1.Now(),
 	}
 
+	err = dm.resourceMgr.PatchStatus(ctx, newInstall, &install)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't record last applied inputs for %q", installRef.Name)
+	}
 	return nil
 }
 
@@ -366,44 +648,47 @@ func (dm *DeployManager) createJob(ctx context.Context, deployInfo DeployInfo) e
 			//Args:            []string{"-c", "sleep 3600"},
 			SecurityContext: &securityContext,
 			VolumeMounts:    volumeMounts,
+			// The outputs action saves the install's outputs to its configmap
+			Env: []corev1.EnvVar{
+				{Name: "INSTALL_CONFIGMAP", Value: deployInfo.configMap},
+				{Name: "INSTALL_NAMESPACE", Value: deployInfo.Namespace},
+			},
 		},
 	}
 
-	// Setup parameter volume
+	// Setup parameter volume, combining the configmap and the secret
 	volumes := []corev1.Volume{
 		{
-			Name: nameWithAction + "-config",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: deployInfo.configMap,
-					},
-				},
-			},
+			Name:         nameWithAction + "-config",
+			VolumeSource: configVolumeSource(deployInfo.configMap, deployInfo.secret, false),
 		},
 	}
 
-	// Setup required inputs volumes
+	// Setup required inputs volumes. A required install deployed before secrets were split out may not have a secret.
 	for _, require := range deployInfo.requires {
 		baseName := getResourceName(require.Name, require.Suffix)
 		configmapName := baseName + "-config"
+		secretName := baseName + "-secrets"
 
 		volumes = append(volumes, corev1.Volume{
-			Name: configmapName,
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: configmapName,
-					},
-				},
-			},
+			Name:         configmapName,
+			VolumeSource: configVolumeSource(configmapName, secretName, true),
 		},
 		)
-		log.WithFields(log.Fields{"name": require.Name, "suffix": require.Suffix, "configmap": configmapName}).Debug("Mounting input volume")
+		log.WithFields(log.Fields{"name": require.Name, "suffix": require.Suffix, "configmap": configmapName, "secret": secretName}).Debug("Mounting input volume")
 	}
 
 	gracePeriod := int64(1)
 	deadline := int64(deployInfo.Timeout/time.Second) + gracePeriod*2
+	podSpec := corev1.PodSpec{
+		ActiveDeadlineSeconds:         &deadline,
+		TerminationGracePeriodSeconds: &gracePeriod,
+		RestartPolicy:                 "Never", // ensures pod remains after job completes or fails
+		Containers:                    containers,
+		Volumes:                       volumes,
+	}
+	applyDeployJobSpec(&podSpec, deployInfo.deployJob)
+
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      nameWithAction,
@@ -413,13 +698,7 @@ func (dm *DeployManager) createJob(ctx context.Context, deployInfo DeployInfo) e
 			BackoffLimit:          &backoffLimit,
 			ActiveDeadlineSeconds: &deadline,
 			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					ActiveDeadlineSeconds:         &deadline,
-					TerminationGracePeriodSeconds: &gracePeriod,
-					RestartPolicy:                 "Never", // ensures pod remains after job completes or fails
-					Containers:                    containers,
-					Volumes:                       volumes,
-				},
+				Spec: podSpec,
 			},
 		},
 	}
@@ -431,13 +710,112 @@ func (dm *DeployManager) createJob(ctx context.Context, deployInfo DeployInfo) e
 	return nil
 }
 
+// configVolumeSource projects a configmap and a secret into a single volume
+func configVolumeSource(configmapName, secretName string, secretOptional bool) corev1.VolumeSource {
+	return corev1.VolumeSource{
+		Projected: &corev1.ProjectedVolumeSource{
+			Sources: []corev1.VolumeProjection{
+				{
+					ConfigMap: &corev1.ConfigMapProjection{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: configmapName,
+						},
+					},
+				},
+				{
+					Secret: &corev1.SecretProjection{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: secretName,
+						},
+						Optional: &secretOptional,
+					},
+				},
+			},
+		},
+	}
+}
+
+// mergeDeployJobSpec overlays the install's deploy job customizations on the application's. Environment variables and
+// node selectors are merged by name, other fields set on the install replace the application's.
+func mergeDeployJobSpec(appSpec, installSpec *v1alpha1.DeployJobSpec) *v1alpha1.DeployJobSpec {
+	if appSpec == nil {
+		return installSpec
+	}
+	if installSpec == nil {
+		return appSpec
+	}
+
+	merged := appSpec.DeepCopy()
+	if installSpec.ServiceAccountName != "" {
+		merged.ServiceAccountName = installSpec.ServiceAccountName
+	}
+	if installSpec.Resources != nil {
+		merged.Resources = installSpec.Resources.DeepCopy()
+	}
+	if len(installSpec.NodeSelector) > 0 {
+		if merged.NodeSelector == nil {
+			merged.NodeSelector = make(map[string]string, len(installSpec.NodeSelector))
+		}
+		for key, value := range installSpec.NodeSelector {
+			merged.NodeSelector[key] = value
+		}
+	}
+	if len(installSpec.Tolerations) > 0 {
+		merged.Tolerations = installSpec.Tolerations
+	}
+	if len(installSpec.ImagePullSecrets) > 0 {
+		merged.ImagePullSecrets = installSpec.ImagePullSecrets
+	}
+	for _, env := range installSpec.Env {
+		found := false
+		for i := range merged.Env {
+			if merged.Env[i].Name == env.Name {
+				merged.Env[i] = env
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Env = append(merged.Env, env)
+		}
+	}
+	merged.EnvFrom = append(merged.EnvFrom, installSpec.EnvFrom...)
+	if installSpec.SecurityContext != nil {
+		merged.SecurityContext = installSpec.SecurityContext.DeepCopy()
+	}
+
+	return merged
+}
+
+// applyDeployJobSpec applies deploy job customizations to the pod spec of a deploy job
+func applyDeployJobSpec(podSpec *corev1.PodSpec, deployJob *v1alpha1.DeployJobSpec) {
+	if deployJob == nil {
+		return
+	}
+
+	podSpec.ServiceAccountName = deployJob.ServiceAccountName
+	podSpec.NodeSelector = deployJob.NodeSelector
+	podSpec.Tolerations = deployJob.Tolerations
+	podSpec.ImagePullSecrets = deployJob.ImagePullSecrets
+
+	container := &podSpec.Containers[0]
+	if deployJob.Resources != nil {
+		container.Resources = *deployJob.Resources
+	}
+	container.Env = append(container.Env, deployJob.Env...)
+	container.EnvFrom = append(container.EnvFrom, deployJob.EnvFrom...)
+	if deployJob.SecurityContext != nil {
+		container.SecurityContext = deployJob.SecurityContext
+	}
+}
+
 func (dm *DeployManager) pollJob(ctx context.Context, deployInfo DeployInfo, installRef InstallReference, showLogs bool) error {
-	fmt.Printf("Waiting %v for action '%s' on %s...\n", deployInfo.Timeout, deployInfo.Action, deployInfo.Name)
+	fmt.Fprintf(deployInfo.out, "Waiting %v for action '%s' on %s...\n", deployInfo.Timeout, deployInfo.Action, deployInfo.Name)
 	start := time.Now()
 
 	var w io.Writer
 	if showLogs {
-		w = os.Stdout
+		w = deployInfo.out
 	} else {
 		w = io.Discard
 	}
@@ -470,18 +848,18 @@ func (dm *DeployManager) pollJob(ctx context.Context, deployInfo DeployInfo, ins
 	}
 
 	if latestStatus == batchv1.JobComplete {
-		log.WithFields(log.Fields{"elapsed": time.Since(start).Round(time.Second)}).Info("Job complete")
+		log.WithFields(log.Fields{"install": deployInfo.Name, "elapsed": time.Since(start).Round(time.Second)}).Info("Job complete")
 		return nil
 	}
 
 	// Failure occurred, print logs if we're not already
 	if !showLogs {
-		err := dm.printLogs(ctx, installRef, os.Stdout)
+		err := dm.printLogs(ctx, installRef, deployInfo.out)
 		if err != nil {
 			log.WithField("error", err).Error("Printing logs failed")
 		}
 	}
-	log.WithFields(log.Fields{"elapsed": time.Since(start).Round(time.Second)}).Error("Job failed")
+	log.WithFields(log.Fields{"install": deployInfo.Name, "elapsed": time.Since(start).Round(time.Second)}).Error("Job failed")
 
 	if latestStatus == batchv1.JobFailed {
 		return errors.New("deploy failed")
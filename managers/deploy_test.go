@@ -0,0 +1,316 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInputsHashIncludesRequires(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clientgoscheme.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flavor := &v1alpha1.Flavor{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+	dbApp := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:                 "db",
+			Version:              "1.0.0",
+			ParameterDefinitions: []v1alpha1.ParameterDefinitionSpec{{Name: "replicas", Default: "1"}},
+		},
+	}
+	webApp := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:     "web",
+			Version:  "1.0.0",
+			Requires: []v1alpha1.RequiresList{{Name: "db"}},
+		},
+	}
+	db := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "db", Version: "1.0.0", Flavor: "default"},
+	}
+	web := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "web", Version: "1.0.0", Flavor: "default"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(flavor, dbApp, webApp, db, web).Build()
+	dm := NewDeployManager(KBClient{Client: c})
+	webRef := InstallReference{Name: "web", Namespace: "default"}
+
+	before, err := dm.InputsHash(context.Background(), webRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var install v1alpha1.Install
+	err = c.Get(context.Background(), client.ObjectKey{Name: "db", Namespace: "default"}, &install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	install.Spec.Parameters = []v1alpha1.ParameterSpec{{Name: "replicas", Value: "3"}}
+	err = c.Update(context.Background(), &install)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := dm.InputsHash(context.Background(), webRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("expected the hash of web to change with the parameters of the db install it requires")
+	}
+}
+
+func TestMergeDeployJobSpec(t *testing.T) {
+	nonRoot := true
+	appSpec := &v1alpha1.DeployJobSpec{
+		ServiceAccountName: "deployer",
+		NodeSelector:       map[string]string{"pool": "system", "zone": "a"},
+		Tolerations:        []corev1.Toleration{{Key: "dedicated", Value: "system"}},
+		Env:                []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "REGION", Value: "us"}},
+		EnvFrom:            []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}}}},
+	}
+
+	tests := []struct {
+		name        string
+		appSpec     *v1alpha1.DeployJobSpec
+		installSpec *v1alpha1.DeployJobSpec
+		expected    *v1alpha1.DeployJobSpec
+	}{
+		{
+			name: "neither",
+		},
+		{
+			name:     "application only",
+			appSpec:  appSpec,
+			expected: appSpec,
+		},
+		{
+			name:        "install only",
+			installSpec: &v1alpha1.DeployJobSpec{ServiceAccountName: "custom"},
+			expected:    &v1alpha1.DeployJobSpec{ServiceAccountName: "custom"},
+		},
+		{
+			name:    "install overrides",
+			appSpec: appSpec,
+			installSpec: &v1alpha1.DeployJobSpec{
+				ServiceAccountName: "custom",
+				NodeSelector:       map[string]string{"zone": "b"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Value: "deploy"}},
+				Env:                []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "DEBUG", Value: "1"}},
+				EnvFrom:            []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "install"}}}},
+				SecurityContext:    &corev1.SecurityContext{RunAsNonRoot: &nonRoot},
+			},
+			expected: &v1alpha1.DeployJobSpec{
+				ServiceAccountName: "custom",
+				NodeSelector:       map[string]string{"pool": "system", "zone": "b"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Value: "deploy"}},
+				Env:                []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "REGION", Value: "us"}, {Name: "DEBUG", Value: "1"}},
+				EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app"}}},
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "install"}}},
+				},
+				SecurityContext: &corev1.SecurityContext{RunAsNonRoot: &nonRoot},
+			},
+		},
+		{
+			name:        "empty install spec keeps the application's",
+			appSpec:     appSpec,
+			installSpec: &v1alpha1.DeployJobSpec{},
+			expected:    appSpec,
+		},
+	}
+
+	for _, test := range tests {
+		before := appSpec.DeepCopy()
+		merged := mergeDeployJobSpec(test.appSpec, test.installSpec)
+		if !equality.Semantic.DeepEqual(merged, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, merged)
+		}
+		if !equality.Semantic.DeepEqual(appSpec, before) {
+			t.Errorf("%s: the application's deploy job spec was modified", test.name)
+		}
+	}
+}
+
+func TestApplyDeployJobSpec(t *testing.T) {
+	limits := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}
+	baseEnv := []corev1.EnvVar{{Name: "INSTALL_NAMESPACE", Value: "default"}}
+
+	tests := []struct {
+		name      string
+		deployJob *v1alpha1.DeployJobSpec
+		expected  corev1.PodSpec
+	}{
+		{
+			name: "no customizations",
+			expected: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "deploy", Env: baseEnv}},
+			},
+		},
+		{
+			name: "customizations",
+			deployJob: &v1alpha1.DeployJobSpec{
+				ServiceAccountName: "deployer",
+				Resources:          &corev1.ResourceRequirements{Limits: limits},
+				NodeSelector:       map[string]string{"pool": "system"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Value: "system"}},
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+				Env:                []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+			},
+			expected: corev1.PodSpec{
+				ServiceAccountName: "deployer",
+				NodeSelector:       map[string]string{"pool": "system"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Value: "system"}},
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+				Containers: []corev1.Container{{
+					Name:      "deploy",
+					Env:       append(append([]corev1.EnvVar{}, baseEnv...), corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}),
+					Resources: corev1.ResourceRequirements{Limits: limits},
+				}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		podSpec := corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "deploy", Env: append([]corev1.EnvVar{}, baseEnv...)}},
+		}
+		applyDeployJobSpec(&podSpec, test.deployJob)
+		if !equality.Semantic.DeepEqual(podSpec, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, podSpec)
+		}
+	}
+}
+
+func TestGetConfigDataSplitsSecrets(t *testing.T) {
+	smtp := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "smtp", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("smtp-pass")},
+	}
+	license := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "license", Namespace: "default"},
+		Data:       map[string]string{"key": "license-key"},
+	}
+	generated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail-generated", Namespace: "default"},
+		Data:       map[string][]byte{"apiKey": []byte("generated-key")},
+	}
+	dm := NewDeployManager(KBClient{Interface: kubefake.NewSimpleClientset(smtp, license, generated)})
+
+	deployInfo := DeployInfo{
+		Name:      "mail",
+		Namespace: "default",
+		definitions: []v1alpha1.ParameterDefinitionSpec{
+			{Name: "host", Default: "smtp.local"},
+			{Name: "adminPassword", Sensitive: true, Default: "hunter2"},
+			{Name: "apiKey", GenerateSecret: v1alpha1.GenerateSecret{Format: "hex", Bytes: 16}},
+			{Name: "smtpPassword"},
+			{Name: "licenseKey"},
+			{Name: "auth", Default: "{{ .params.host }}:{{ .params.adminPassword }}", Template: true},
+		},
+		parameters: []v1alpha1.ParameterSpec{
+			{Name: "adminPassword", Value: "override"},
+			{Name: "smtpPassword", ValueFrom: &v1alpha1.ParameterValueSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "smtp"},
+				Key:                  "password",
+			}}},
+			{Name: "licenseKey", ValueFrom: &v1alpha1.ParameterValueSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "license"},
+				Key:                  "key",
+			}}},
+		},
+		installSpec: v1alpha1.InstallSpec{
+			Application: "mail",
+			Secrets:     []v1alpha1.ParameterSpec{{Name: "webhookToken", Value: "token"}},
+		},
+	}
+	deployInfo.installSpec.Parameters = deployInfo.parameters
+
+	data, secretData, err := dm.getConfigData(deployInfo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters := make(map[string]string)
+	err = json.Unmarshal([]byte(data[ParametersFile]), &parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := make(map[string]string)
+	err = json.Unmarshal([]byte(secretData[SecretsFile]), &secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		value  string
+		secret bool
+	}{
+		{name: "host", value: "smtp.local"},
+		{name: "licenseKey", value: "license-key"},
+		{name: "adminPassword", value: "override", secret: true},
+		{name: "apiKey", value: "generated-key", secret: true},
+		{name: "smtpPassword", value: "smtp-pass", secret: true},
+		{name: "auth", value: "smtp.local:override", secret: true},
+		{name: "webhookToken", value: "token", secret: true},
+	}
+	for _, test := range tests {
+		found, other := parameters, secrets
+		if test.secret {
+			found, other = secrets, parameters
+		}
+		if found[test.name] != test.value {
+			t.Errorf("expected %s to be %q, got %q", test.name, test.value, found[test.name])
+		}
+		if _, leaked := other[test.name]; leaked {
+			t.Errorf("expected %s to be in only one of parameters and secrets", test.name)
+		}
+	}
+
+	for _, value := range []string{"override", "smtp-pass", "generated-key", "token"} {
+		for file, content := range data {
+			if strings.Contains(content, value) {
+				t.Errorf("expected %s to be kept out of %s", value, file)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+	DiffError     = "error"
+
+	DiffFormatText  = "text"
+	DiffFormatJSON  = "json"
+	DiffFormatPatch = "patch"
+
+	diffMarkerBegin     = "kb-diff: begin"
+	diffMarkerChanged   = "kb-diff: changed"
+	diffMarkerUnchanged = "kb-diff: unchanged"
+)
+
+// ManifestDiffOpts controls how the installs of a manifest are diffed
+type ManifestDiffOpts struct {
+	Timeout time.Duration
+
+	// Parallelism is the maximum number of diff jobs run at once
+	Parallelism int
+}
+
+// InstallDiff is the outcome of diffing a single install from a manifest
+type InstallDiff struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+
+	// Patch is the unified diff printed by the diff job, if any
+	Patch string `json:"patch,omitempty"`
+
+	// Output is everything printed by the diff job
+	Output string `json:"output,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Diff runs the diff jobs of all the installs created from this manifest, including suffixed installs, and returns
+// the outcome of each in dependency order. The jobs run concurrently and their output is captured. An error is
+// returned along with the diffs if any diff job failed.
+func (mm *ManifestManager) Diff(ctx context.Context, manifestRef ManifestReference, opts ManifestDiffOpts) ([]InstallDiff, error) {
+	manifest, err := mm.getManifest(ctx, manifestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := mm.resolveInstallGraph(ctx, manifest, manifestRef.Namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := manifestRef.Selection.selectInstalls(graph)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, layer := range filterLayers(graph.layers, selected) {
+		names = append(names, layer...)
+	}
+
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	diffs := make([]InstallDiff, len(names))
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			diffs[i] = mm.diffInstall(ctx, InstallReference{Name: name, Namespace: manifestRef.Namespace}, opts.Timeout)
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, diff := range diffs {
+		if diff.Result == DiffError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return diffs, fmt.Errorf("couldn't diff %d of %d installs", failed, len(diffs))
+	}
+	return diffs, nil
+}
+
+// diffInstall runs the diff job of an install and captures its output
+func (mm *ManifestManager) diffInstall(ctx context.Context, installRef InstallReference, timeout time.Duration) InstallDiff {
+	var out bytes.Buffer
+	deployOpts := DeployOpts{
+		Action:  ActionDiff,
+		Timeout: timeout,
+		Out:     &out,
+	}
+
+	log.WithField("install", installRef.Name).Info("Running diff")
+	err := mm.deployMgr.Deploy(ctx, installRef, deployOpts, true)
+	diff := InstallDiff{
+		Name:   installRef.Name,
+		Output: out.String(),
+	}
+	patch, changed := parseDiffOutput(out.String())
+	switch {
+	case err != nil:
+		diff.Result = DiffError
+		diff.Error = err.Error()
+	case changed:
+		diff.Patch = patch
+		diff.Result = DiffChanged
+	default:
+		diff.Result = DiffUnchanged
+	}
+	return diff
+}
+
+// parseDiffOutput returns the patch in the output of a diff job and whether the install changed. The diff scripts of
+// the bases print the patch between a "kb-diff: begin" line and a "kb-diff: changed" or "kb-diff: unchanged" line.
+// Output from older bases without these markers falls back to looking for the first file header of a unified diff.
+func parseDiffOutput(output string) (string, bool) {
+	lines := strings.SplitAfter(output, "\n")
+
+	begin := -1
+	for i, line := range lines {
+		marker := strings.TrimSpace(line)
+		switch marker {
+		case diffMarkerBegin:
+			begin = i + 1
+		case diffMarkerChanged, diffMarkerUnchanged:
+			if begin < 0 {
+				continue
+			}
+			if marker == diffMarkerUnchanged {
+				return "", false
+			}
+			return strings.Join(lines[begin:i], ""), true
+		}
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff ") || (strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")) {
+			return strings.Join(lines[i:], ""), true
+		}
+	}
+	return "", false
+}
+
+// WriteInstallDiffs writes the outcome of a manifest diff. The text format shows the patch of each changed install,
+// or the full output of every install with showOutput, followed by a summary table. The patch format concatenates the
+// patches of all changed installs into a single unified diff.
+func WriteInstallDiffs(w io.Writer, diffs []InstallDiff, format string, showOutput bool) error {
+	switch format {
+	case DiffFormatJSON:
+		if !showOutput {
+			trimmed := make([]InstallDiff, len(diffs))
+			for i, diff := range diffs {
+				diff.Output = ""
+				trimmed[i] = diff
+			}
+			diffs = trimmed
+		}
+		b, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "couldn't encode diffs")
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+
+	case DiffFormatPatch:
+		for _, diff := range diffs {
+			if diff.Patch == "" {
+				continue
+			}
+			patch := diff.Patch
+			if !strings.HasSuffix(patch, "\n") {
+				patch += "\n"
+			}
+			_, err := io.WriteString(w, patch)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case DiffFormatText:
+		for _, diff := range diffs {
+			text := diff.Patch
+			if showOutput {
+				text = diff.Output
+			}
+			if text == "" {
+				continue
+			}
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			fmt.Fprintf(w, "=== %s ===\n%s\n", diff.Name, text)
+		}
+
+		tw := tabwriter.NewWriter(w, 1, 3, 3, ' ', 0)
+		fmt.Fprintf(tw, "NAME\tRESULT\tERROR\n")
+		for _, diff := range diffs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", diff.Name, diff.Result, diff.Error)
+		}
+		return tw.Flush()
+	}
+
+	return fmt.Errorf("unknown diff format '%s'; expected %s, %s or %s", format, DiffFormatText, DiffFormatJSON, DiffFormatPatch)
+}
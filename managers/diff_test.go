@@ -0,0 +1,161 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		patch   string
+		changed bool
+	}{
+		{
+			name: "helm changed",
+			output: "+ helm diff upgrade\n" +
+				"kb-diff: begin\n" +
+				"default, web, Deployment (apps) has changed:\n" +
+				"-  replicas: 1\n" +
+				"+  replicas: 2\n" +
+				"kb-diff: changed\n",
+			patch:   "default, web, Deployment (apps) has changed:\n-  replicas: 1\n+  replicas: 2\n",
+			changed: true,
+		},
+		{
+			name:   "helm unchanged",
+			output: "+ helm diff upgrade\nkb-diff: begin\nkb-diff: unchanged\n",
+		},
+		{
+			name: "static changed",
+			output: "kb-diff: begin\n" +
+				"diff -u -N /tmp/LIVE/apps.v1.Deployment.default.web /tmp/MERGED/apps.v1.Deployment.default.web\n" +
+				"--- /tmp/LIVE/apps.v1.Deployment.default.web\n" +
+				"+++ /tmp/MERGED/apps.v1.Deployment.default.web\n" +
+				"-  replicas: 1\n" +
+				"+  replicas: 2\n" +
+				"kb-diff: changed\n",
+			patch: "diff -u -N /tmp/LIVE/apps.v1.Deployment.default.web /tmp/MERGED/apps.v1.Deployment.default.web\n" +
+				"--- /tmp/LIVE/apps.v1.Deployment.default.web\n" +
+				"+++ /tmp/MERGED/apps.v1.Deployment.default.web\n" +
+				"-  replicas: 1\n" +
+				"+  replicas: 2\n",
+			changed: true,
+		},
+		{
+			name:   "static unchanged",
+			output: "kb-diff: begin\nkb-diff: unchanged\n",
+		},
+		{
+			name: "qbec changed",
+			output: "kb-diff: begin\n" +
+				"--- live deployments web -n default (source web.jsonnet)\n" +
+				"+++ config deployments web -n default (source web.jsonnet)\n" +
+				"-  replicas: 1\n" +
+				"+  replicas: 2\n" +
+				"\n" +
+				"---\n" +
+				"stats:\n" +
+				"  changes:\n" +
+				"  - deployments web -n default (source web.jsonnet)\n" +
+				"kb-diff: changed\n",
+			patch: "--- live deployments web -n default (source web.jsonnet)\n" +
+				"+++ config deployments web -n default (source web.jsonnet)\n" +
+				"-  replicas: 1\n" +
+				"+  replicas: 2\n" +
+				"\n" +
+				"---\n" +
+				"stats:\n" +
+				"  changes:\n" +
+				"  - deployments web -n default (source web.jsonnet)\n",
+			changed: true,
+		},
+		{
+			name:   "qbec unchanged",
+			output: "kb-diff: begin\n---\nstats:\n  same: 1\nkb-diff: unchanged\n",
+		},
+		{
+			name: "without markers",
+			output: "Waiting 1m0s for action 'diff' on web...\n" +
+				"--- a/web\n" +
+				"+++ b/web\n" +
+				"+  replicas: 2\n",
+			patch:   "--- a/web\n+++ b/web\n+  replicas: 2\n",
+			changed: true,
+		},
+		{
+			name:   "without markers or changes",
+			output: "Waiting 1m0s for action 'diff' on web...\n--- no changes\n",
+		},
+	}
+
+	for _, test := range tests {
+		patch, changed := parseDiffOutput(test.output)
+		if patch != test.patch || changed != test.changed {
+			t.Errorf("%s: expected changed=%v with patch:\n%s\ngot changed=%v with patch:\n%s", test.name, test.changed, test.patch, changed, patch)
+		}
+	}
+}
+
+func TestWriteInstallDiffs(t *testing.T) {
+	diffs := []InstallDiff{
+		{Name: "redis", Result: DiffUnchanged, Output: "no changes\n"},
+		{Name: "web", Result: DiffChanged, Patch: "--- a\n+++ b\n", Output: "Waiting\n--- a\n+++ b\n"},
+		{Name: "postgres-auth", Result: DiffError, Error: "job failed"},
+	}
+
+	var buf bytes.Buffer
+	err := WriteInstallDiffs(&buf, diffs, DiffFormatPatch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "--- a\n+++ b\n" {
+		t.Errorf("unexpected patch output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	err = WriteInstallDiffs(&buf, diffs, DiffFormatJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []InstallDiff
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 || decoded[1].Output != "" || decoded[2].Error != "job failed" {
+		t.Errorf("unexpected json output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	err = WriteInstallDiffs(&buf, diffs, DiffFormatText, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "=== web ===") || strings.Contains(buf.String(), "=== redis ===") {
+		t.Errorf("unexpected text output:\n%s", buf.String())
+	}
+
+	if err := WriteInstallDiffs(&buf, diffs, "xml", false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,260 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DriftNone    = "none"
+	DriftFound   = "drifted"
+	DriftFixed   = "remediated"
+	DriftSkipped = "skipped"
+	DriftPending = "pending"
+	DriftError   = "error"
+)
+
+// 1 if the install's live resources differ from the resources rendered by its bundle, 0 if they don't
+var installDriftGauge = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kb_install_drift",
+		Help: "Whether the resources of an install were changed outside of kb.",
+	}, []string{"install", "namespace"})
+
+type DriftManager struct {
+	kbClient    KBClient
+	resourceMgr *ResourceManager
+	deployMgr   *DeployManager
+	graphMgr    *GraphManager
+}
+
+func NewDriftManager(kbClient KBClient) *DriftManager {
+	return &DriftManager{
+		kbClient:    kbClient,
+		resourceMgr: NewResourceManager(kbClient),
+		deployMgr:   NewDeployManager(kbClient),
+		graphMgr:    NewGraphManager(kbClient),
+	}
+}
+
+type DriftOpts struct {
+	// Timeout is the timeout of each diff and apply job
+	Timeout time.Duration
+
+	// Remediate re-applies installs that drifted
+	Remediate bool
+}
+
+// DriftResult is the outcome of checking a single install for drift
+type DriftResult struct {
+	Name   string
+	Result string
+	Patch  string
+	Error  string
+}
+
+// Check runs the diff job of an install, records whether it drifted in the install's status and, with
+// opts.Remediate, re-applies it. Installs that were never deployed are skipped. Installs whose version or inputs
+// changed since they were deployed aren't checked: their diff would show the pending changes rather than drift, and
+// the diff job would write the pending inputs to the install's config.
+func (dm *DriftManager) Check(ctx context.Context, installRef InstallReference, opts DriftOpts) DriftResult {
+	result := DriftResult{Name: installRef.Name}
+
+	var install v1alpha1.Install
+	err := dm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &install)
+	if err != nil {
+		result.Result = DriftError
+		result.Error = fmt.Sprintf("couldn't get install: %v", err)
+		return result
+	}
+
+	switch dm.graphMgr.installStatus(ctx, &install) {
+	case StatusApplied:
+	case StatusChanged, StatusOutdated:
+		log.WithField("install", installRef.Name).Debug("Not checking for drift since the install has undeployed changes")
+		result.Result = DriftPending
+		return result
+	default:
+		result.Result = DriftSkipped
+		return result
+	}
+
+	var out bytes.Buffer
+	deployOpts := DeployOpts{
+		Action:  ActionDiff,
+		Timeout: opts.Timeout,
+		Out:     &out,
+	}
+	log.WithField("install", installRef.Name).Debug("Checking for drift")
+	err = dm.deployMgr.Deploy(ctx, installRef, deployOpts, true)
+
+	drift := &v1alpha1.DriftStatus{LastChecked: metav1.Now()}
+	if install.Status.Drift != nil {
+		drift.LastRemediated = install.Status.Drift.LastRemediated
+	}
+
+	switch {
+	case err != nil:
+		result.Result = DriftError
+		result.Error = err.Error()
+		drift.Error = err.Error()
+		if install.Status.Drift != nil {
+			drift.Drifted = install.Status.Drift.Drifted
+		}
+	default:
+		result.Patch, drift.Drifted = parseDiffOutput(out.String())
+		result.Result = DriftNone
+		if drift.Drifted {
+			result.Result = DriftFound
+		}
+	}
+
+	if drift.Drifted && opts.Remediate && err == nil {
+		err := dm.remediate(ctx, installRef, &install, opts.Timeout)
+		if err != nil {
+			result.Result = DriftError
+			result.Error = err.Error()
+			drift.Error = err.Error()
+		} else {
+			now := metav1.Now()
+			result.Result = DriftFixed
+			drift.Drifted = false
+			drift.LastRemediated = &now
+		}
+	}
+
+	value := 0.0
+	if drift.Drifted {
+		value = 1
+	}
+	installDriftGauge.WithLabelValues(installRef.Name, installRef.Namespace).Set(value)
+
+	err = dm.recordDrift(ctx, installRef, drift)
+	if err != nil && result.Error == "" {
+		result.Result = DriftError
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// CheckAll checks every install in the namespace for drift
+func (dm *DriftManager) CheckAll(ctx context.Context, namespace string, opts DriftOpts) ([]DriftResult, error) {
+	var list v1alpha1.InstallList
+	err := dm.resourceMgr.List(ctx, namespace, &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list installs")
+	}
+
+	results := make([]DriftResult, 0, len(list.Items))
+	for _, install := range list.Items {
+		installRef := InstallReference{Name: install.Name, Namespace: namespace}
+		results = append(results, dm.Check(ctx, installRef, opts))
+	}
+	return results, nil
+}
+
+// Run checks every install in the namespace for drift every interval until the context is cancelled
+func (dm *DriftManager) Run(ctx context.Context, namespace string, interval time.Duration, opts DriftOpts) {
+	for {
+		results, err := dm.CheckAll(ctx, namespace, opts)
+		if err != nil {
+			log.WithField("err", err).Warn("couldn't check installs for drift")
+		}
+		for _, result := range results {
+			fields := log.Fields{"install": result.Name, "result": result.Result}
+			switch result.Result {
+			case DriftError:
+				log.WithFields(fields).WithField("err", result.Error).Warn("Couldn't check install for drift")
+			case DriftFound, DriftFixed:
+				log.WithFields(fields).Info("Install drifted")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// remediate re-applies an install and records the apply, keeping the manifest generation that last deployed it
+func (dm *DriftManager) remediate(ctx context.Context, installRef InstallReference, install *v1alpha1.Install, timeout time.Duration) error {
+	log.WithField("install", installRef.Name).Info("Remediating drift")
+
+	var out bytes.Buffer
+	deployOpts := DeployOpts{
+		Action:  ActionApply,
+		Timeout: timeout,
+		Out:     &out,
+	}
+	err := dm.deployMgr.Deploy(ctx, installRef, deployOpts, false)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't re-apply install: %s", out.String())
+	}
+
+	lastApplied := install.Status.LastApplied
+	return dm.deployMgr.RecordApplied(ctx, installRef, lastApplied.ManifestGeneration, lastApplied.InputsHash)
+}
+
+// recordDrift stores the result of a drift check in the install's status
+func (dm *DriftManager) recordDrift(ctx context.Context, installRef InstallReference, drift *v1alpha1.DriftStatus) error {
+	var install v1alpha1.Install
+	err := dm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &install)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't get install %q", installRef.Name)
+	}
+
+	newInstall := install.DeepCopy()
+	newInstall.Status.Drift = drift
+
+	err = dm.resourceMgr.PatchStatus(ctx, newInstall, &install)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't record drift for %q", installRef.Name)
+	}
+	return nil
+}
+
+// WriteDriftResults writes the outcome of a drift check, showing the patch of each drifted install
+func WriteDriftResults(w io.Writer, results []DriftResult) error {
+	for _, result := range results {
+		if result.Patch == "" {
+			continue
+		}
+		fmt.Fprintf(w, "=== %s ===\n%s\n", result.Name, result.Patch)
+	}
+
+	tw := tabwriter.NewWriter(w, 1, 3, 3, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tDRIFT\tERROR\n")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Name, result.Result, result.Error)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckSkipsUndeployedChanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdated := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "web", Version: "2.0.0"},
+		Status: v1alpha1.InstallStatus{
+			LastApplied: &v1alpha1.LastAppliedStatus{Version: "1.0.0", InputsHash: "abc"},
+		},
+	}
+	pending := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "db", Version: "1.0.0"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(outdated, pending).WithStatusSubresource(outdated).Build()
+	dm := NewDriftManager(KBClient{Client: c})
+
+	// Neither install may run a diff job, which would fail without a clientset
+	result := dm.Check(context.Background(), InstallReference{Name: "web", Namespace: "default"}, DriftOpts{Remediate: true})
+	if result.Result != DriftPending {
+		t.Errorf("expected %s for an outdated install, got %s (%s)", DriftPending, result.Result, result.Error)
+	}
+	result = dm.Check(context.Background(), InstallReference{Name: "db", Namespace: "default"}, DriftOpts{})
+	if result.Result != DriftSkipped {
+		t.Errorf("expected %s for an undeployed install, got %s (%s)", DriftSkipped, result.Result, result.Error)
+	}
+
+	var install v1alpha1.Install
+	err = c.Get(context.Background(), client.ObjectKey{Name: "web", Namespace: "default"}, &install)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if install.Status.Drift != nil {
+		t.Errorf("expected no drift to be recorded, got %+v", install.Status.Drift)
+	}
+}
@@ -0,0 +1,448 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// encryptedPrefix marks values encrypted by encryptValue. The rest of the value is
+	// <key id>:<base64 wrapped data key>:<base64 nonce and ciphertext>
+	encryptedPrefix = "kbenc:v1:"
+
+	keySize = 32
+
+	// currentKeyAnnotation names the data key of the encryption key Secret holding the current key
+	currentKeyAnnotation = "bundle.splunk.com/current-key"
+)
+
+// KeyProvider wraps and unwraps the data keys used to encrypt secret values. Each value is encrypted with its own data
+// key, and only the wrapped data key is stored next to it.
+type KeyProvider interface {
+	// CurrentKeyID returns the id of the key that wraps new data keys
+	CurrentKeyID(ctx context.Context) (string, error)
+
+	// WrapKey encrypts a data key with the current key, and returns the id of the key used
+	WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error)
+
+	// UnwrapKey decrypts a data key wrapped by the key with the given id
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KeyRotator is implemented by key providers that can create a new current key themselves
+type KeyRotator interface {
+	// RotateKey creates a new key, makes it the current key, and returns its id. Older keys are kept for decryption.
+	RotateKey(ctx context.Context) (string, error)
+}
+
+// isEncrypted returns whether a stored value was encrypted by encryptValue
+func isEncrypted(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(encryptedPrefix))
+}
+
+// encryptValue encrypts a value with a new data key wrapped by the key provider. Without a key provider, the value is
+// returned as is.
+func encryptValue(ctx context.Context, keyProvider KeyProvider, plaintext []byte) ([]byte, error) {
+	if keyProvider == nil {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	_, err := crand.Read(dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate data key")
+	}
+
+	ciphertext, err := sealAESGCM(dataKey, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, wrapped, err := keyProvider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to wrap data key")
+	}
+
+	value := fmt.Sprintf("%s%s:%s:%s", encryptedPrefix, keyID,
+		base64.StdEncoding.EncodeToString(wrapped), base64.StdEncoding.EncodeToString(ciphertext))
+	return []byte(value), nil
+}
+
+// decryptValue decrypts a value encrypted by encryptValue. Values stored before encryption was enabled are returned
+// as is.
+func decryptValue(ctx context.Context, keyProvider KeyProvider, value []byte) ([]byte, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	if keyProvider == nil {
+		return nil, errors.New("value is encrypted, but no encryption key is configured")
+	}
+
+	keyID, wrapped, ciphertext, err := parseEncryptedValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := keyProvider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to unwrap data key with key %q", keyID)
+	}
+
+	return openAESGCM(dataKey, ciphertext)
+}
+
+// needsReencryption returns whether a stored value isn't encrypted with the current key
+func needsReencryption(value []byte, currentKeyID string) bool {
+	if !isEncrypted(value) {
+		return true
+	}
+	keyID, _, _, err := parseEncryptedValue(value)
+	return err != nil || keyID != currentKeyID
+}
+
+func parseEncryptedValue(value []byte) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(string(value), encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("malformed encrypted value")
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "malformed wrapped data key")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "malformed ciphertext")
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+// sealAESGCM encrypts plaintext with AES-GCM, prepending the random nonce
+func sealAESGCM(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = crand.Read(nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate nonce")
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAESGCM decrypts a value sealed by sealAESGCM
+func openAESGCM(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decrypt value")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid encryption key")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher")
+	}
+	return gcm, nil
+}
+
+// keyring holds AES keys by id, one of which is current
+type keyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+func (k *keyring) wrap(dataKey []byte) (string, []byte, error) {
+	key, found := k.keys[k.current]
+	if !found {
+		return "", nil, errors.New("no current encryption key")
+	}
+	wrapped, err := sealAESGCM(key, dataKey)
+	return k.current, wrapped, err
+}
+
+func (k *keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, found := k.keys[keyID]
+	if !found {
+		return nil, fmt.Errorf("unknown encryption key %q", keyID)
+	}
+	return openAESGCM(key, wrapped)
+}
+
+// LocalKeyProvider reads keys from a local file. Each line holds a key id and a base64 encoded 32 byte key, separated
+// by whitespace. The first key is the current key, and the others are only used for decryption. Lines starting with #
+// are ignored.
+type LocalKeyProvider struct {
+	keyring keyring
+}
+
+func NewLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read encryption key file %q", path)
+	}
+
+	lp := &LocalKeyProvider{keyring: keyring{keys: make(map[string][]byte)}}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("%s:%d: expected a key id and a base64 encoded key", path, lineNumber)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%s:%d: key must be %d base64 encoded bytes", path, lineNumber, keySize)
+		}
+
+		lp.keyring.keys[fields[0]] = key
+		if lp.keyring.current == "" {
+			lp.keyring.current = fields[0]
+		}
+	}
+	if lp.keyring.current == "" {
+		return nil, fmt.Errorf("no keys in encryption key file %q", path)
+	}
+
+	return lp, nil
+}
+
+func (lp *LocalKeyProvider) CurrentKeyID(ctx context.Context) (string, error) {
+	return lp.keyring.current, nil
+}
+
+func (lp *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	return lp.keyring.wrap(dataKey)
+}
+
+func (lp *LocalKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	return lp.keyring.unwrap(keyID, wrapped)
+}
+
+// SecretKeyProvider keeps keys in a Kubernetes Secret, keyed by key id. The current key is named by an annotation. The
+// Secret and a first key are created on first use.
+type SecretKeyProvider struct {
+	kbClient  KBClient
+	namespace string
+	name      string
+
+	mu      sync.Mutex
+	keyring *keyring
+}
+
+func NewSecretKeyProvider(kbClient KBClient, namespace, name string) *SecretKeyProvider {
+	return &SecretKeyProvider{
+		kbClient:  kbClient,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (sp *SecretKeyProvider) CurrentKeyID(ctx context.Context) (string, error) {
+	k, err := sp.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	return k.current, nil
+}
+
+func (sp *SecretKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	k, err := sp.load(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return k.wrap(dataKey)
+}
+
+func (sp *SecretKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	k, err := sp.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.unwrap(keyID, wrapped)
+}
+
+func (sp *SecretKeyProvider) RotateKey(ctx context.Context) (string, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	var keyID string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := sp.getOrCreate(ctx)
+		if err != nil {
+			return err
+		}
+
+		keyID, err = addKey(secret)
+		if err != nil {
+			return err
+		}
+
+		secret, err = sp.kbClient.Interface.CoreV1().Secrets(sp.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		sp.keyring, err = keyringFromSecret(secret)
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "couldn't rotate encryption key in secret %q", sp.name)
+	}
+
+	log.WithFields(log.Fields{"secret": sp.name, "key": keyID}).Info("Created new encryption key")
+	return keyID, nil
+}
+
+// load reads the keys once, creating the Secret if needed
+func (sp *SecretKeyProvider) load(ctx context.Context) (*keyring, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.keyring != nil {
+		return sp.keyring, nil
+	}
+
+	secret, err := sp.getOrCreate(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get encryption key secret %q", sp.name)
+	}
+	sp.keyring, err = keyringFromSecret(secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid encryption key secret %q", sp.name)
+	}
+	return sp.keyring, nil
+}
+
+func (sp *SecretKeyProvider) getOrCreate(ctx context.Context) (*corev1.Secret, error) {
+	secretClient := sp.kbClient.Interface.CoreV1().Secrets(sp.namespace)
+
+	secret, err := secretClient.Get(ctx, sp.name, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		return secret, err
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sp.name,
+			Namespace: sp.namespace,
+		},
+	}
+	_, err = addKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err = secretClient.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Another process created the keys first
+		return secretClient.Get(ctx, sp.name, metav1.GetOptions{})
+	}
+	return secret, err
+}
+
+// addKey adds a new random key to the Secret and makes it current
+func addKey(secret *corev1.Secret) (string, error) {
+	key := make([]byte, keySize)
+	_, err := crand.Read(key)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate encryption key")
+	}
+
+	keyID := "key-" + time.Now().UTC().Format("20060102T150405Z")
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	for i := 1; secret.Data[keyID] != nil; i++ {
+		keyID = fmt.Sprintf("key-%s-%d", time.Now().UTC().Format("20060102T150405Z"), i)
+	}
+	secret.Data[keyID] = key
+
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[currentKeyAnnotation] = keyID
+	return keyID, nil
+}
+
+func keyringFromSecret(secret *corev1.Secret) (*keyring, error) {
+	k := &keyring{
+		keys:    make(map[string][]byte, len(secret.Data)),
+		current: secret.Annotations[currentKeyAnnotation],
+	}
+	for keyID, key := range secret.Data {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", keyID, keySize)
+		}
+		k.keys[keyID] = key
+	}
+
+	// Fall back to the newest key if the annotation is missing
+	if k.current == "" {
+		keyIDs := make([]string, 0, len(k.keys))
+		for keyID := range k.keys {
+			keyIDs = append(keyIDs, keyID)
+		}
+		sort.Strings(keyIDs)
+		if len(keyIDs) > 0 {
+			k.current = keyIDs[len(keyIDs)-1]
+		}
+	}
+	if _, found := k.keys[k.current]; !found {
+		return nil, fmt.Errorf("current key %q not found", k.current)
+	}
+	return k, nil
+}
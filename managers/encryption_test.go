@@ -0,0 +1,152 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeyFile(t *testing.T, keyIDs ...string) string {
+	t.Helper()
+	var lines []string
+	for _, keyID := range keyIDs {
+		key := make([]byte, keySize)
+		_, err := rand.Read(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", keyID, base64.StdEncoding.EncodeToString(key)))
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	err := os.WriteFile(path, []byte("# test keys\n"+strings.Join(lines, "\n")+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEncryptValueRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keyProvider, err := NewLocalKeyProvider(writeKeyFile(t, "k1"))
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+
+	encrypted, err := encryptValue(ctx, keyProvider, []byte("hunter2"))
+	if err != nil {
+		t.Fatalf("encryptValue: %v", err)
+	}
+	if !isEncrypted(encrypted) || strings.Contains(string(encrypted), "hunter2") {
+		t.Fatalf("expected an encrypted value, got %q", encrypted)
+	}
+
+	decrypted, err := decryptValue(ctx, keyProvider, encrypted)
+	if err != nil {
+		t.Fatalf("decryptValue: %v", err)
+	}
+	if string(decrypted) != "hunter2" {
+		t.Errorf("expected hunter2, got %q", decrypted)
+	}
+
+	_, err = decryptValue(ctx, nil, encrypted)
+	if err == nil {
+		t.Error("expected an error decrypting without a key provider")
+	}
+}
+
+func TestDecryptPlaintextValue(t *testing.T) {
+	decrypted, err := decryptValue(context.Background(), nil, []byte("plain"))
+	if err != nil || string(decrypted) != "plain" {
+		t.Errorf("expected values stored before encryption to be returned as is, got %q, %v", decrypted, err)
+	}
+}
+
+func TestLocalKeyProviderRotation(t *testing.T) {
+	ctx := context.Background()
+	path := writeKeyFile(t, "old")
+	oldProvider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptValue(ctx, oldProvider, []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Put a new key first, keeping the old key for decryption
+	b, err := os.ReadFile(writeKeyFile(t, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, append(b, old...), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newProvider, err := NewLocalKeyProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentKeyID, _ := newProvider.CurrentKeyID(ctx)
+	if currentKeyID != "new" {
+		t.Fatalf("expected the first key to be current, got %q", currentKeyID)
+	}
+	if !needsReencryption(encrypted, currentKeyID) {
+		t.Error("expected a value encrypted with the old key to need reencryption")
+	}
+	if !needsReencryption([]byte("plain"), currentKeyID) {
+		t.Error("expected a plaintext value to need reencryption")
+	}
+
+	decrypted, err := decryptValue(ctx, newProvider, encrypted)
+	if err != nil || string(decrypted) != "value" {
+		t.Fatalf("expected the old key to still decrypt, got %q, %v", decrypted, err)
+	}
+	reencrypted, err := encryptValue(ctx, newProvider, decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsReencryption(reencrypted, currentKeyID) {
+		t.Error("expected a value encrypted with the current key not to need reencryption")
+	}
+}
+
+func TestLocalKeyProviderInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	for _, content := range []string{"", "k1\n", "k1 not-base64!\n", "k1 " + base64.StdEncoding.EncodeToString([]byte("short")) + "\n"} {
+		err := os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewLocalKeyProvider(path)
+		if err == nil {
+			t.Errorf("expected an error for key file %q", content)
+		}
+	}
+}
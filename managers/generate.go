@@ -0,0 +1,364 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	v1alpha1 "github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+const (
+	DefaultPasswordLength   = 32
+	DefaultBase64Bytes      = 32
+	DefaultCertValidityDays = 365
+
+	CharsetLower   = "lower"
+	CharsetUpper   = "upper"
+	CharsetDigits  = "digits"
+	CharsetSymbols = "symbols"
+
+	// TLS values are json objects with these keys
+	TLSCertKey = "tls.crt"
+	TLSKeyKey  = "tls.key"
+	TLSCAKey   = "ca.crt"
+)
+
+var (
+	defaultCharsets = []string{CharsetLower, CharsetUpper, CharsetDigits}
+
+	charsetCharacters = map[string]string{
+		CharsetLower:   "abcdefghijklmnopqrstuvwxyz",
+		CharsetUpper:   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		CharsetDigits:  "0123456789",
+		CharsetSymbols: "!#%+-.:=?@^_~",
+	}
+)
+
+// certAuthorityFunc returns the CA used to sign generated tls certificates
+type certAuthorityFunc func() (*x509.Certificate, crypto.Signer, error)
+
+// generatePassword returns a random password of the given length. Every charset appears at least once.
+func generatePassword(length int, charsets []string, symbols string) (string, error) {
+	if length < 0 {
+		return "", fmt.Errorf("password length %d is negative", length)
+	}
+	if length == 0 {
+		length = DefaultPasswordLength
+	}
+	if len(charsets) == 0 {
+		charsets = defaultCharsets
+	}
+	if length < len(charsets) {
+		return "", fmt.Errorf("password length %d is shorter than the number of charsets", length)
+	}
+
+	classes := make([]string, 0, len(charsets))
+	for _, charset := range charsets {
+		characters, found := charsetCharacters[charset]
+		if !found {
+			return "", fmt.Errorf("unknown charset '%s'", charset)
+		}
+		if charset == CharsetSymbols && symbols != "" {
+			characters = symbols
+		}
+		classes = append(classes, characters)
+	}
+	all := strings.Join(classes, "")
+
+	// Start with one character of each class, fill the rest from all classes, then shuffle
+	password := make([]byte, 0, length)
+	for _, characters := range classes {
+		c, err := randomChar(characters)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomChar(characters string) (byte, error) {
+	i, err := randomInt(len(characters))
+	if err != nil {
+		return 0, err
+	}
+	return characters[i], nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := crand.Int(crand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to generate random value")
+	}
+	return int(n.Int64()), nil
+}
+
+// generateHex returns the given number of random bytes, hex encoded
+func generateHex(bytes int) (string, error) {
+	if bytes < 0 {
+		return "", fmt.Errorf("byte count %d is negative", bytes)
+	}
+	randomBytes := make([]byte, bytes)
+	_, err := crand.Read(randomBytes)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate random value")
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// generateBase64 returns the given number of random bytes, base64 encoded
+func generateBase64(bytes int) (string, error) {
+	if bytes < 0 {
+		return "", fmt.Errorf("byte count %d is negative", bytes)
+	}
+	if bytes == 0 {
+		bytes = DefaultBase64Bytes
+	}
+	randomBytes := make([]byte, bytes)
+	_, err := crand.Read(randomBytes)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate random value")
+	}
+	return base64.StdEncoding.EncodeToString(randomBytes), nil
+}
+
+// generateUUID returns a random (version 4) UUID
+func generateUUID() (string, error) {
+	u, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate uuid")
+	}
+	return u.String(), nil
+}
+
+// generateECDSAKey returns a PEM encoded ecdsa private key on the named curve
+func generateECDSAKey(curveName string) (string, error) {
+	key, err := newECDSAKey(curveName)
+	if err != nil {
+		return "", err
+	}
+	return encodePKCS8PrivateKeyToPEM(key)
+}
+
+func newECDSAKey(curveName string) (*ecdsa.PrivateKey, error) {
+	var curve elliptic.Curve
+	switch curveName {
+	case "", "P256":
+		curve = elliptic.P256()
+	case "P384":
+		curve = elliptic.P384()
+	case "P521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unknown curve '%s'", curveName)
+	}
+
+	key, err := ecdsa.GenerateKey(curve, crand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate ecdsa key")
+	}
+	return key, nil
+}
+
+// generateEd25519Key returns a PEM encoded ed25519 private key
+func generateEd25519Key() (string, error) {
+	_, key, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate ed25519 key")
+	}
+	return encodePKCS8PrivateKeyToPEM(key)
+}
+
+func encodePKCS8PrivateKeyToPEM(key crypto.PrivateKey) (string, error) {
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode private key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b})), nil
+}
+
+// generateTLS returns a json object holding a new leaf key and certificate signed by the CA, and the CA certificate
+func generateTLS(generateSecret v1alpha1.GenerateSecret, getCA certAuthorityFunc) (string, error) {
+	if getCA == nil {
+		return "", errors.New("no certificate authority available")
+	}
+	caCert, caKey, err := getCA()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get certificate authority")
+	}
+
+	key, err := newECDSAKey(generateSecret.Curve)
+	if err != nil {
+		return "", err
+	}
+
+	validityDays := generateSecret.ValidityDays
+	if validityDays == 0 {
+		validityDays = DefaultCertValidityDays
+	}
+
+	commonName := generateSecret.CommonName
+	if commonName == "" && len(generateSecret.DNSNames) > 0 {
+		commonName = generateSecret.DNSNames[0]
+	}
+
+	var ips []net.IP
+	for _, address := range generateSecret.IPAddresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return "", fmt.Errorf("invalid ip address '%s'", address)
+		}
+		ips = append(ips, ip)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     generateSecret.DNSNames,
+		IPAddresses:  ips,
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(time.Duration(validityDays) * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to create certificate")
+	}
+
+	keyPEM, err := encodePKCS8PrivateKeyToPEM(key)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(map[string]string{
+		TLSCertKey: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		TLSKeyKey:  keyPEM,
+		TLSCAKey:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encode certificate")
+	}
+	return string(b), nil
+}
+
+// generateCA returns a new self-signed CA certificate and key, PEM encoded
+func generateCA(commonName string, validity time.Duration) ([]byte, []byte, error) {
+	key, err := newECDSAKey("P256")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to create CA certificate")
+	}
+
+	keyPEM, err := encodePKCS8PrivateKeyToPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), []byte(keyPEM), nil
+}
+
+// parseCA decodes a PEM encoded CA certificate and key
+func parseCA(certPEM, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, errors.New("CA certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse CA certificate")
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, errors.New("CA key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to parse CA key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("CA key can't sign certificates")
+	}
+	return cert, signer, nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serial, err := crand.Int(crand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate serial number")
+	}
+	return serial, nil
+}
@@ -0,0 +1,296 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	v1alpha1 "github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+func testCA(t *testing.T) certAuthorityFunc {
+	t.Helper()
+	certPEM, keyPEM, err := generateCA("test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("generateCA: %v", err)
+	}
+	return func() (*x509.Certificate, crypto.Signer, error) {
+		return parseCA(certPEM, keyPEM)
+	}
+}
+
+func generate(t *testing.T, generateSecret v1alpha1.GenerateSecret) string {
+	t.Helper()
+	value, err := generateSecretValue(generateSecret, testCA(t))
+	if err != nil {
+		t.Fatalf("generateSecretValue(%+v): %v", generateSecret, err)
+	}
+	return value
+}
+
+func parsePKCS8(t *testing.T, value string) crypto.PrivateKey {
+	t.Helper()
+	block, _ := pem.Decode([]byte(value))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("expected a PEM encoded private key, got %q", value)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParsePKCS8PrivateKey: %v", err)
+	}
+	return key
+}
+
+func TestGenerateHex(t *testing.T) {
+	value := generate(t, v1alpha1.GenerateSecret{Format: "hex", Bytes: 16})
+	b, err := hex.DecodeString(value)
+	if err != nil || len(b) != 16 {
+		t.Errorf("expected 16 hex encoded bytes, got %q", value)
+	}
+}
+
+func TestGenerateRSA(t *testing.T) {
+	value := generate(t, v1alpha1.GenerateSecret{Format: "rsa", Bits: 1024})
+	block, _ := pem.Decode([]byte(value))
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		t.Fatalf("expected a PEM encoded rsa key, got %q", value)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParsePKCS1PrivateKey: %v", err)
+	}
+	if key.N.BitLen() != 1024 {
+		t.Errorf("expected a 1024 bit key, got %d", key.N.BitLen())
+	}
+}
+
+func TestGenerateBase64(t *testing.T) {
+	for _, tc := range []struct {
+		bytes    int
+		expected int
+	}{
+		{bytes: 0, expected: DefaultBase64Bytes},
+		{bytes: 48, expected: 48},
+	} {
+		value := generate(t, v1alpha1.GenerateSecret{Format: "base64", Bytes: tc.bytes})
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || len(b) != tc.expected {
+			t.Errorf("expected %d base64 encoded bytes, got %q", tc.expected, value)
+		}
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		spec     v1alpha1.GenerateSecret
+		length   int
+		required []string
+		allowed  string
+	}{
+		{
+			name:     "defaults",
+			spec:     v1alpha1.GenerateSecret{Format: "password"},
+			length:   DefaultPasswordLength,
+			required: []string{charsetCharacters[CharsetLower], charsetCharacters[CharsetUpper], charsetCharacters[CharsetDigits]},
+			allowed:  charsetCharacters[CharsetLower] + charsetCharacters[CharsetUpper] + charsetCharacters[CharsetDigits],
+		},
+		{
+			name:     "all classes",
+			spec:     v1alpha1.GenerateSecret{Format: "password", Length: 12, Charsets: []string{CharsetLower, CharsetUpper, CharsetDigits, CharsetSymbols}},
+			length:   12,
+			required: []string{charsetCharacters[CharsetLower], charsetCharacters[CharsetUpper], charsetCharacters[CharsetDigits], charsetCharacters[CharsetSymbols]},
+			allowed:  charsetCharacters[CharsetLower] + charsetCharacters[CharsetUpper] + charsetCharacters[CharsetDigits] + charsetCharacters[CharsetSymbols],
+		},
+		{
+			name:     "custom symbols",
+			spec:     v1alpha1.GenerateSecret{Format: "password", Length: 4, Charsets: []string{CharsetDigits, CharsetSymbols}, Symbols: "$"},
+			length:   4,
+			required: []string{charsetCharacters[CharsetDigits], "$"},
+			allowed:  charsetCharacters[CharsetDigits] + "$",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Generate several passwords, since characters are chosen at random
+			for i := 0; i < 20; i++ {
+				value := generate(t, tc.spec)
+				if len(value) != tc.length {
+					t.Fatalf("expected length %d, got %q", tc.length, value)
+				}
+				for _, characters := range tc.required {
+					if !strings.ContainsAny(value, characters) {
+						t.Fatalf("expected one of %q in %q", characters, value)
+					}
+				}
+				for _, c := range value {
+					if !strings.ContainsRune(tc.allowed, c) {
+						t.Fatalf("unexpected character %q in %q", c, value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratePasswordErrors(t *testing.T) {
+	for _, spec := range []v1alpha1.GenerateSecret{
+		{Format: "password", Charsets: []string{"emoji"}},
+		{Format: "password", Length: 2, Charsets: []string{CharsetLower, CharsetUpper, CharsetDigits}},
+		{Format: "password", Length: -1},
+	} {
+		_, err := generateSecretValue(spec, nil)
+		if err == nil {
+			t.Errorf("expected an error for %+v", spec)
+		}
+	}
+}
+
+func TestGenerateUUID(t *testing.T) {
+	value := generate(t, v1alpha1.GenerateSecret{Format: "uuid"})
+	u, err := uuid.Parse(value)
+	if err != nil {
+		t.Fatalf("expected a uuid, got %q", value)
+	}
+	if u.Version() != 4 {
+		t.Errorf("expected a version 4 uuid, got version %d", u.Version())
+	}
+}
+
+func TestGenerateECDSA(t *testing.T) {
+	for curveName, curve := range map[string]elliptic.Curve{
+		"":     elliptic.P256(),
+		"P256": elliptic.P256(),
+		"P384": elliptic.P384(),
+		"P521": elliptic.P521(),
+	} {
+		value := generate(t, v1alpha1.GenerateSecret{Format: "ecdsa", Curve: curveName})
+		key, ok := parsePKCS8(t, value).(*ecdsa.PrivateKey)
+		if !ok {
+			t.Fatalf("expected an ecdsa key for curve %q", curveName)
+		}
+		if key.Curve != curve {
+			t.Errorf("expected curve %s, got %s", curve.Params().Name, key.Curve.Params().Name)
+		}
+	}
+
+	_, err := generateSecretValue(v1alpha1.GenerateSecret{Format: "ecdsa", Curve: "P128"}, nil)
+	if err == nil {
+		t.Error("expected an error for an unknown curve")
+	}
+}
+
+func TestGenerateEd25519(t *testing.T) {
+	value := generate(t, v1alpha1.GenerateSecret{Format: "ed25519"})
+	if _, ok := parsePKCS8(t, value).(ed25519.PrivateKey); !ok {
+		t.Errorf("expected an ed25519 key, got %q", value)
+	}
+}
+
+func TestGenerateTLS(t *testing.T) {
+	getCA := testCA(t)
+	caCert, _, err := getCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := v1alpha1.GenerateSecret{
+		Format:       "tls",
+		DNSNames:     []string{"nginx.default.svc", "nginx"},
+		IPAddresses:  []string{"10.0.0.1"},
+		ValidityDays: 30,
+	}
+	value, err := generateSecretValue(spec, getCA)
+	if err != nil {
+		t.Fatalf("generateSecretValue: %v", err)
+	}
+
+	m := make(map[string]string)
+	err = json.Unmarshal([]byte(value), &m)
+	if err != nil {
+		t.Fatalf("expected a json object, got %q", value)
+	}
+
+	block, _ := pem.Decode([]byte(m[TLSCertKey]))
+	if block == nil {
+		t.Fatalf("expected a PEM encoded certificate, got %q", m[TLSCertKey])
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	if cert.Subject.CommonName != "nginx.default.svc" {
+		t.Errorf("expected the first DNS name as common name, got %q", cert.Subject.CommonName)
+	}
+	if len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "10.0.0.1" {
+		t.Errorf("unexpected ip addresses %v", cert.IPAddresses)
+	}
+	if validity := cert.NotAfter.Sub(time.Now()); validity > 31*24*time.Hour || validity < 29*24*time.Hour {
+		t.Errorf("expected the certificate to be valid for 30 days, got %v", validity)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "nginx", Roots: roots})
+	if err != nil {
+		t.Errorf("certificate not signed by the CA: %v", err)
+	}
+
+	if m[TLSCAKey] != string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})) {
+		t.Error("expected the CA certificate in the value")
+	}
+
+	key := parsePKCS8(t, m[TLSKeyKey])
+	if !cert.PublicKey.(*ecdsa.PublicKey).Equal(key.(crypto.Signer).Public()) {
+		t.Error("key doesn't match the certificate")
+	}
+}
+
+func TestGenerateTLSInvalidIP(t *testing.T) {
+	_, err := generateSecretValue(v1alpha1.GenerateSecret{Format: "tls", IPAddresses: []string{"not-an-ip"}}, testCA(t))
+	if err == nil {
+		t.Error("expected an error for an invalid ip address")
+	}
+}
+
+func TestGenerateUnknownFormat(t *testing.T) {
+	_, err := generateSecretValue(v1alpha1.GenerateSecret{Format: "morse"}, nil)
+	if err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestGenerateNegativeBytes(t *testing.T) {
+	for _, format := range []string{"hex", "base64"} {
+		_, err := generateSecretValue(v1alpha1.GenerateSecret{Format: format, Bytes: -1}, nil)
+		if err == nil {
+			t.Errorf("expected an error for %s with negative bytes", format)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+	GraphFormatASCII   = "ascii"
+
+	// requiresAnnotation holds a json list of the installs an install created from a manifest depends on
+	requiresAnnotation = "bundle.splunk.com/requires"
+
+	// StatusPending is an install that hasn't been deployed, or that has generated values the next deploy will create
+	StatusPending = "pending"
+
+	// StatusApplied is an install deployed with its current inputs
+	StatusApplied = "applied"
+
+	// StatusOutdated is an install whose version changed since it was deployed
+	StatusOutdated = "outdated"
+
+	// StatusChanged is an install whose parameters, flavor or dependency outputs changed since it was deployed
+	StatusChanged = "changed"
+
+	// StatusUnknown is an install whose inputs couldn't be determined
+	StatusUnknown = "unknown"
+)
+
+// GraphNode is an install in a dependency graph
+type GraphNode struct {
+	Name    string
+	Version string
+	Layer   int
+	Status  string
+
+	// Requires lists the installs this install depends on
+	Requires []string
+}
+
+// InstallGraph is the dependency graph of a set of installs. Nodes are sorted by layer and then by name.
+type InstallGraph struct {
+	Name  string
+	Nodes []GraphNode
+}
+
+type GraphManager struct {
+	kbClient    KBClient
+	resourceMgr *ResourceManager
+	manifestMgr *ManifestManager
+	deployMgr   *DeployManager
+}
+
+func NewGraphManager(kbClient KBClient) *GraphManager {
+	return &GraphManager{
+		kbClient:    kbClient,
+		resourceMgr: NewResourceManager(kbClient),
+		manifestMgr: NewManifestManager(kbClient),
+		deployMgr:   NewDeployManager(kbClient),
+	}
+}
+
+// ManifestGraph returns the graph of the installs created from a manifest, including suffixed installs, in the layers
+// they're deployed in. Installs that don't exist yet are left out.
+func (gm *GraphManager) ManifestGraph(ctx context.Context, manifestRef ManifestReference) (*InstallGraph, error) {
+	manifest, err := gm.manifestMgr.getManifest(ctx, manifestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := gm.manifestMgr.resolveInstallGraph(ctx, manifest, manifestRef.Namespace, true)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &InstallGraph{Name: manifest.Name}
+	for layer, names := range resolved.layers {
+		for _, name := range names {
+			install, found := resolved.installs[name]
+			if !found {
+				continue
+			}
+			graph.Nodes = append(graph.Nodes, GraphNode{
+				Name:     name,
+				Version:  install.Spec.Version,
+				Layer:    layer,
+				Status:   gm.installStatus(ctx, install),
+				Requires: existingDependencies(resolved.dependencies[name], resolved.installs),
+			})
+		}
+	}
+	graph.sort()
+	return graph, nil
+}
+
+// InstallsGraph returns the graph of all installs in a namespace, using the requires of their applications and, for
+// installs created from a manifest, the requires recorded when the manifest was installed
+func (gm *GraphManager) InstallsGraph(ctx context.Context, namespace string) (*InstallGraph, error) {
+	var list v1alpha1.InstallList
+	err := gm.resourceMgr.List(ctx, namespace, &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list installs")
+	}
+
+	installs := make(map[string]*v1alpha1.Install, len(list.Items))
+	for i := range list.Items {
+		installs[list.Items[i].Name] = &list.Items[i]
+	}
+
+	dependencies := make(map[string][]string, len(list.Items))
+	for name, install := range installs {
+		appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
+		var app v1alpha1.Application
+		err := gm.resourceMgr.Get(ctx, appName, namespace, &app)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get application %q", appName)
+		}
+
+		var deps []string
+		for _, require := range app.Spec.Requires {
+			deps = append(deps, getResourceName(require.Name, require.Suffix))
+		}
+		if value, found := install.Annotations[requiresAnnotation]; found {
+			var recorded []string
+			err := json.Unmarshal([]byte(value), &recorded)
+			if err != nil {
+				log.WithFields(log.Fields{"install": name, "err": err}).Warn("Ignoring invalid requires annotation")
+			}
+			deps = append(deps, recorded...)
+		}
+		dependencies[name] = existingDependencies(deps, installs)
+	}
+
+	layers, err := graphLayers(dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &InstallGraph{Name: namespace}
+	for name, install := range installs {
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			Name:     name,
+			Version:  install.Spec.Version,
+			Layer:    layers[name],
+			Status:   gm.installStatus(ctx, install),
+			Requires: dependencies[name],
+		})
+	}
+	graph.sort()
+	return graph, nil
+}
+
+// installStatus compares an install with its last successful deploy
+func (gm *GraphManager) installStatus(ctx context.Context, install *v1alpha1.Install) string {
+	lastApplied := install.Status.LastApplied
+	if lastApplied == nil {
+		return StatusPending
+	}
+	if lastApplied.Version != install.Spec.Version {
+		return StatusOutdated
+	}
+
+	// Generated values that don't exist yet are left to the next deploy rather than created to compute the hash
+	hash, err := gm.deployMgr.PeekInputsHash(ctx, InstallReference{Name: install.Name, Namespace: install.Namespace})
+	if errors.Cause(err) == errNotGenerated {
+		return StatusPending
+	} else if err != nil {
+		return StatusUnknown
+	}
+	if hash != lastApplied.InputsHash {
+		return StatusChanged
+	}
+	return StatusApplied
+}
+
+// existingDependencies returns the dependencies that are installs in the graph, sorted and without duplicates
+func existingDependencies(deps []string, installs map[string]*v1alpha1.Install) []string {
+	var result []string
+	for _, dep := range deps {
+		if _, found := installs[dep]; found && !stringSliceContains(result, dep) {
+			result = append(result, dep)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// graphLayers assigns each node the layer after the highest layer of its dependencies
+func graphLayers(dependencies map[string][]string) (map[string]int, error) {
+	layers := make(map[string]int, len(dependencies))
+	visiting := make(map[string]bool)
+
+	var visit func(name string) (int, error)
+	visit = func(name string) (int, error) {
+		if layer, found := layers[name]; found {
+			return layer, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("dependency cycle through install %q", name)
+		}
+		visiting[name] = true
+
+		layer := 0
+		for _, dep := range dependencies[name] {
+			depLayer, err := visit(dep)
+			if err != nil {
+				return 0, err
+			}
+			if depLayer+1 > layer {
+				layer = depLayer + 1
+			}
+		}
+		layers[name] = layer
+		return layer, nil
+	}
+
+	for name := range dependencies {
+		_, err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+func (g *InstallGraph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Layer != g.Nodes[j].Layer {
+			return g.Nodes[i].Layer < g.Nodes[j].Layer
+		}
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+}
+
+func (n GraphNode) annotation() string {
+	return fmt.Sprintf("layer %d, %s, %s", n.Layer, n.Version, n.Status)
+}
+
+// Write writes the graph in the given format
+func (g *InstallGraph) Write(w io.Writer, format string) error {
+	switch format {
+	case GraphFormatDOT:
+		return g.writeDOT(w)
+	case GraphFormatMermaid:
+		return g.writeMermaid(w)
+	case GraphFormatASCII:
+		return g.writeASCII(w)
+	}
+	return fmt.Errorf("unknown graph format '%s'; expected %s, %s or %s", format, GraphFormatDOT, GraphFormatMermaid, GraphFormatASCII)
+}
+
+// writeDOT writes the graph in graphviz format, with edges pointing from an install to the installs it requires
+func (g *InstallGraph) writeDOT(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.Name)
+	fmt.Fprintf(&b, "  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q];\n", node.Name, node.Name+"\n"+node.annotation())
+	}
+	for _, node := range g.Nodes {
+		for _, require := range node.Requires {
+			fmt.Fprintf(&b, "  %q -> %q;\n", node.Name, require)
+		}
+	}
+	fmt.Fprintf(&b, "}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid writes the graph as a mermaid flowchart. Node ids are generated since install names may contain
+// characters mermaid doesn't allow in ids.
+func (g *InstallGraph) writeMermaid(w io.Writer) error {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph TD\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s<br/>%s\"]\n", ids[node.Name], node.Name, node.annotation())
+	}
+	for _, node := range g.Nodes {
+		for _, require := range node.Requires {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[node.Name], ids[require])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeASCII writes the graph as a tree starting from the installs nothing else requires, with each install's
+// requirements below it. Installs required by several others are repeated under each.
+func (g *InstallGraph) writeASCII(w io.Writer) error {
+	nodes := make(map[string]GraphNode, len(g.Nodes))
+	required := make(map[string]bool)
+	for _, node := range g.Nodes {
+		nodes[node.Name] = node
+		for _, require := range node.Requires {
+			required[require] = true
+		}
+	}
+
+	var roots []string
+	for _, node := range g.Nodes {
+		if !required[node.Name] {
+			roots = append(roots, node.Name)
+		}
+	}
+	sort.Strings(roots)
+
+	var b strings.Builder
+	var writeNode func(name, prefix string, last, root bool)
+	writeNode = func(name, prefix string, last, root bool) {
+		node := nodes[name]
+		childPrefix := prefix
+		if root {
+			fmt.Fprintf(&b, "%s (%s)\n", node.Name, node.annotation())
+		} else if last {
+			fmt.Fprintf(&b, "%s└── %s (%s)\n", prefix, node.Name, node.annotation())
+			childPrefix += "    "
+		} else {
+			fmt.Fprintf(&b, "%s├── %s (%s)\n", prefix, node.Name, node.annotation())
+			childPrefix += "│   "
+		}
+		for i, require := range node.Requires {
+			writeNode(require, childPrefix, i == len(node.Requires)-1, false)
+		}
+	}
+	for _, root := range roots {
+		writeNode(root, "", true, true)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,168 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGraphLayers(t *testing.T) {
+	layers, err := graphLayers(map[string][]string{
+		"web":           {"postgres-auth", "redis"},
+		"auth":          {"postgres-auth"},
+		"postgres-auth": nil,
+		"redis":         nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"web": 1, "auth": 1, "postgres-auth": 0, "redis": 0}
+	for name, layer := range expected {
+		if layers[name] != layer {
+			t.Errorf("expected %s in layer %d, got %d", name, layer, layers[name])
+		}
+	}
+
+	_, err = graphLayers(map[string][]string{"a": {"b"}, "b": {"a"}})
+	if err == nil {
+		t.Error("expected cycle error")
+	}
+}
+
+func TestInstallGraphWrite(t *testing.T) {
+	graph := &InstallGraph{
+		Name: "app",
+		Nodes: []GraphNode{
+			{Name: "postgres-auth", Version: "13.0.0", Layer: 0, Status: StatusApplied},
+			{Name: "redis", Version: "7.0.0", Layer: 0, Status: StatusPending},
+			{Name: "web", Version: "1.0.0", Layer: 1, Status: StatusChanged, Requires: []string{"postgres-auth", "redis"}},
+		},
+	}
+
+	var b strings.Builder
+	err := graph.Write(&b, GraphFormatASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `web (layer 1, 1.0.0, changed)
+├── postgres-auth (layer 0, 13.0.0, applied)
+└── redis (layer 0, 7.0.0, pending)
+`
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	err = graph.Write(&b, GraphFormatMermaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "n2 --> n0\n") || !strings.Contains(b.String(), `n0["postgres-auth<br/>layer 0, 13.0.0, applied"]`) {
+		t.Errorf("unexpected mermaid output:\n%s", b.String())
+	}
+
+	b.Reset()
+	err = graph.Write(&b, GraphFormatDOT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"web" -> "redis";`) {
+		t.Errorf("unexpected dot output:\n%s", b.String())
+	}
+
+	err = graph.Write(&b, "svg")
+	if err == nil {
+		t.Error("expected unknown format error")
+	}
+}
+
+func TestInstallsGraph(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clientgoscheme.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flavor := &v1alpha1.Flavor{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+	postgresApp := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres-13.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:    "postgres",
+			Version: "13.0.0",
+			ParameterDefinitions: []v1alpha1.ParameterDefinitionSpec{
+				{Name: "password", GenerateSecret: v1alpha1.GenerateSecret{Format: "tls", CommonName: "postgres"}},
+			},
+		},
+	}
+	webApp := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1.0.0", Namespace: "default"},
+		Spec:       v1alpha1.ApplicationSpec{Name: "web", Version: "1.0.0"},
+	}
+	lastApplied := &v1alpha1.LastAppliedStatus{Version: "13.0.0", InputsHash: "abc"}
+	postgres := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres-auth", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "postgres", Version: "13.0.0", Suffix: "auth", Flavor: "default"},
+		Status:     v1alpha1.InstallStatus{LastApplied: lastApplied},
+	}
+	web := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{requiresAnnotation: `["postgres-auth"]`},
+		},
+		Spec: v1alpha1.InstallSpec{Application: "web", Version: "1.0.0", Flavor: "default"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(flavor, postgresApp, webApp, postgres, web).Build()
+	clientset := kubefake.NewSimpleClientset()
+	gm := NewGraphManager(KBClient{Client: c, Interface: clientset})
+
+	graph, err := gm.InstallsGraph(context.Background(), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	err = graph.Write(&b, GraphFormatASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `web (layer 1, 1.0.0, pending)
+└── postgres-auth (layer 0, 13.0.0, pending)
+`
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("expected the graph to only read secrets, got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
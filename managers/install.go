@@ -25,6 +25,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/splunk/kube-bundler/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -35,10 +36,10 @@ type InstallManager struct {
 }
 
 type InstallDescription struct {
-	Name        string
-	Application string
-	Version     string
-	Parameters  map[string]ParameterDesc
+	Name        string                   `json:"name"`
+	Application string                   `json:"application"`
+	Version     string                   `json:"version"`
+	Parameters  map[string]ParameterDesc `json:"parameters"`
 }
 
 func NewInstallManager(kbClient KBClient) *InstallManager {
@@ -93,6 +94,10 @@ func (im *InstallManager) Install(ctx context.Context, appName, name string, nam
 	install.Spec.DockerRegistry = dockerRegistry
 
 	if !foundExisting {
+		err = im.validateParameters(ctx, appName, version, installName, namespace, parameters)
+		if err != nil {
+			return nil, err
+		}
 		install.Spec.Parameters = parameters
 	} else {
 		install.Spec.Parameters = installs.Items[0].Spec.Parameters
@@ -123,39 +128,70 @@ func (im *InstallManager) Install(ctx context.Context, appName, name string, nam
 	return &install, nil
 }
 
-func (im *InstallManager) Describe(ctx context.Context, installs []string) ([]InstallDescription, error) {
+// validateParameters checks parameters against the definitions of the application, if the application is registered
+func (im *InstallManager) validateParameters(ctx context.Context, appName, version, installName, namespace string, parameters []v1alpha1.ParameterSpec) error {
+	var app v1alpha1.Application
+	err := im.resourceMgr.Get(ctx, fmt.Sprintf("%s-%s", appName, version), namespace, &app)
+	if apierrors.IsNotFound(errors.Cause(err)) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "couldn't get application")
+	}
+
+	pm := NewParameterManager(im.kbClient, namespace, installName, app.Spec.ParameterDefinitions, parameters)
+	err = pm.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "invalid parameters for install %q", installName)
+	}
+	return nil
+}
+
+// Describe returns the parameters of the given installs in a namespace, or of all installs if none are given
+func (im *InstallManager) Describe(ctx context.Context, namespace string, installs []string) ([]InstallDescription, error) {
 	var list v1alpha1.InstallList
 	if len(installs) == 0 {
-		err := im.resourceMgr.List(ctx, defaultNamespace, &list)
+		err := im.resourceMgr.List(ctx, namespace, &list)
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't list installs")
 		}
 	} else {
-		var install v1alpha1.Install
-		err := im.resourceMgr.Get(ctx, installs[0], defaultNamespace, &install)
-		if err != nil {
-			return nil, errors.Wrap(err, "couldn't get installs")
+		for _, installName := range installs {
+			var install v1alpha1.Install
+			err := im.resourceMgr.Get(ctx, installName, namespace, &install)
+			if err != nil {
+				return nil, errors.Wrapf(err, "couldn't get install %q", installName)
+			}
+			list.Items = append(list.Items, install)
 		}
-		list.Items = append(list.Items, install)
 	}
 
 	descriptions := make([]InstallDescription, 0)
 	for _, install := range list.Items {
 		appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
 		var app v1alpha1.Application
-		err := im.resourceMgr.Get(ctx, appName, defaultNamespace, &app)
+		err := im.resourceMgr.Get(ctx, appName, namespace, &app)
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't get application")
 		}
 
-		pm := NewParameterManager(im.kbClient, install.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+		// Values read from a Secret or ConfigMap are described by their source, which isn't sensitive
+		valueFrom := make(map[string]bool)
+		for _, parameter := range install.Spec.Parameters {
+			valueFrom[parameter.Name] = parameter.ValueFrom != nil
+		}
+
+		pm := NewParameterManager(im.kbClient, namespace, install.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
 		params := make(map[string]ParameterDesc)
 		for name, parameterDesc := range pm.GetParameterDesc() {
-			params[name] = ParameterDesc{
-				Value:       parameterDesc.Value,
-				Description: parameterDesc.Description,
-				Default:     parameterDesc.Default,
+			if parameterDesc.Sensitive {
+				if parameterDesc.Value != "" && !valueFrom[name] {
+					parameterDesc.Value = maskedValue
+				}
+				if parameterDesc.Default != "" {
+					parameterDesc.Default = maskedValue
+				}
 			}
+			params[name] = parameterDesc
 		}
 
 		descriptions = append(
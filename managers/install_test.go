@@ -0,0 +1,84 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDescribeMasksSensitiveValues(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:    "mail",
+			Version: "1.0.0",
+			ParameterDefinitions: []v1alpha1.ParameterDefinitionSpec{
+				{Name: "host", Default: "smtp.local"},
+				{Name: "password", Sensitive: true, Default: "hunter2"},
+				{Name: "token", Sensitive: true},
+			},
+		},
+	}
+	install := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "mail", Namespace: "default"},
+		Spec: v1alpha1.InstallSpec{
+			Application: "mail",
+			Version:     "1.0.0",
+			Parameters: []v1alpha1.ParameterSpec{
+				{Name: "password", ValueFrom: &v1alpha1.ParameterValueSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "smtp"},
+						Key:                  "password",
+					},
+				}},
+				{Name: "token", Value: "abc123"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(app, install).Build()
+	im := NewInstallManager(KBClient{Client: c})
+
+	descriptions, err := im.Describe(context.Background(), "default", []string{"mail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := descriptions[0].Parameters
+
+	expected := map[string]ParameterDesc{
+		"host":     {Value: "smtp.local", Default: "smtp.local"},
+		"password": {Value: "<from secret smtp/password>", Default: maskedValue},
+		"token":    {Value: maskedValue},
+	}
+	for name, desc := range expected {
+		if params[name].Value != desc.Value || params[name].Default != desc.Default {
+			t.Errorf("expected %s to have value %q and default %q, got %q and %q", name, desc.Value, desc.Default, params[name].Value, params[name].Default)
+		}
+	}
+}
@@ -18,54 +18,139 @@ package managers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/quipo/dependencysolver"
 	log "github.com/sirupsen/logrus"
 	"github.com/splunk/kube-bundler/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type ManifestReference struct {
 	Name      string
 	Namespace string
+
+	// Overlays are merged over the manifest, in order. See MergeManifests.
+	Overlays []*v1alpha1.Manifest
+
+	// Lock, if set, pins the manifest's bundles to the versions and digests in a lockfile
+	Lock *ManifestLock
+
+	// Selection limits deploy, diff, smoketest and import to part of the manifest
+	Selection InstallSelection
+}
+
+// ManifestDeployOpts controls how the installs of a manifest are deployed
+type ManifestDeployOpts struct {
+	ShowLogs bool
+	Timeout  time.Duration
+
+	// Parallelism is the maximum number of installs deployed at once within a dependency layer
+	Parallelism int
+
+	// ContinueOnError keeps deploying installs that don't depend on a failed install
+	ContinueOnError bool
+
+	// Resume skips installs whose inputs haven't changed since their last successful deploy
+	Resume bool
+
+	// Out receives job progress and logs. Defaults to os.Stdout
+	Out io.Writer
+}
+
+// ManifestUninstallOpts controls how the installs of a manifest are uninstalled
+type ManifestUninstallOpts struct {
+	ShowLogs bool
+	Timeout  time.Duration
+
+	// Force continues uninstalling when a delete action fails
+	Force bool
+
+	// Unregister removes the Applications of the uninstalled installs
+	Unregister bool
+}
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultUnchanged = "unchanged"
+	ResultFailed    = "failed"
+	ResultSkipped   = "skipped"
+
+	// ResultAllowedFailure is a failed install whose bundle allows failure
+	ResultAllowedFailure = "failed (allowed)"
+)
+
+// InstallResult is the outcome of deploying a single install from a manifest
+type InstallResult struct {
+	Name     string
+	Layer    int
+	Result   string
+	Duration time.Duration
+	Err      error
 }
 
 type ManifestManager struct {
-	kbClient           KBClient
-	resourceMgr        *ResourceManager
-	registerMgr        *RegisterManager
-	registryMgr        *RegistryManager
-	installMgr         *InstallManager
-	deployMgr          *DeployManager
-	smoketestMgr       *SmoketestManager
-	deploySmoketestMgr *DeploySmoketestManager
+	kbClient     KBClient
+	resourceMgr  *ResourceManager
+	registerMgr  *RegisterManager
+	registryMgr  *RegistryManager
+	installMgr   *InstallManager
+	deployMgr    *DeployManager
+	smoketestMgr *SmoketestManager
 }
 
 func NewManifestManager(kbClient KBClient) *ManifestManager {
 	return &ManifestManager{
-		kbClient:           kbClient,
-		resourceMgr:        NewResourceManager(kbClient),
-		registerMgr:        NewRegisterManager(kbClient),
-		registryMgr:        NewRegistryManager(kbClient),
-		installMgr:         NewInstallManager(kbClient),
-		deployMgr:          NewDeployManager(kbClient),
-		smoketestMgr:       NewSmoketestManager(kbClient),
-		deploySmoketestMgr: NewDeploySmoketestManager(kbClient),
+		kbClient:     kbClient,
+		resourceMgr:  NewResourceManager(kbClient),
+		registerMgr:  NewRegisterManager(kbClient),
+		registryMgr:  NewRegistryManager(kbClient),
+		installMgr:   NewInstallManager(kbClient),
+		deployMgr:    NewDeployManager(kbClient),
+		smoketestMgr: NewSmoketestManager(kbClient),
 	}
 }
 
-// Install installs all the bundles listed in this manifest
-func (mm *ManifestManager) Install(ctx context.Context, manifestRef ManifestReference, force bool) error {
+// Save creates or updates a manifest resource from a manifest read from a file
+func (mm *ManifestManager) Save(ctx context.Context, manifest *v1alpha1.Manifest, namespace string) error {
+	var existing v1alpha1.Manifest
+	err := mm.resourceMgr.CreateOrPatch(ctx, manifest.Name, namespace, &existing, func() error {
+		existing.Labels = manifest.Labels
+		existing.Annotations = manifest.Annotations
+		existing.Spec = manifest.Spec
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "couldn't save manifest %q", manifest.Name)
+	}
+	return nil
+}
+
+// getManifest gets a manifest resource with the reference's overlays merged over it
+func (mm *ManifestManager) getManifest(ctx context.Context, manifestRef ManifestReference) (*v1alpha1.Manifest, error) {
 	var manifest v1alpha1.Manifest
 	err := mm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &manifest)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
+		return nil, errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
+	}
+	return MergeManifests(&manifest, manifestRef.Overlays...), nil
+}
+
+// Install installs all the bundles listed in this manifest
+func (mm *ManifestManager) Install(ctx context.Context, manifestRef ManifestReference, force bool) error {
+	manifest, err := mm.getManifest(ctx, manifestRef)
+	if err != nil {
+		return err
 	}
 
 	// verify all nodes meet minimum CPU/Memory requirements
-	err = verifyResourceRequirements(ctx, *mm.resourceMgr, mm.kbClient, manifest)
+	err = verifyResourceRequirements(ctx, *mm.resourceMgr, mm.kbClient, *manifest)
 	if err != nil {
 		if force {
 			log.Warnf("Forcing installation with insufficient resources for flavor %v", manifest.Spec.Flavor)
@@ -92,21 +177,13 @@ func (mm *ManifestManager) Install(ctx context.Context, manifestRef ManifestRefe
 		bundleRefs = append(bundleRefs, BundleRef{Name: bundle.Name, Version: bundle.Version})
 	}
 
-	var sources []Source
-	for _, sourceInfo := range manifest.Spec.Sources {
-		var src v1alpha1.Source
-		err := mm.resourceMgr.Get(ctx, sourceInfo.Name, manifestRef.Namespace, &src)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't get source '%s'", sourceInfo.Name)
-		}
-
-		newSource, err := NewSource(src.Spec.Type, src.Spec.Path, src.Spec.Options, sourceInfo.Section, sourceInfo.Release)
-		if err != nil {
-			return errors.Wrap(err, "couldn't create new source")
-		}
-		sources = append(sources, newSource)
+	multiSource, err := getManifestSource(ctx, mm.resourceMgr, manifest, manifestRef.Namespace)
+	if err != nil {
+		return err
+	}
+	if manifestRef.Lock != nil {
+		multiSource = NewLockedSource(multiSource, manifestRef.Lock)
 	}
-	multiSource := NewMultiSource(sources)
 
 	// Register the bundles
 	apps, err := mm.registerMgr.RegisterAll(ctx, bundleRefs, multiSource, manifestRef.Namespace)
@@ -139,7 +216,8 @@ func (mm *ManifestManager) Install(ctx context.Context, manifestRef ManifestRefe
 
 	// Install the installs
 	for _, app := range apps {
-		pm := NewParameterManager(mm.kbClient, app.Spec.Name, app.Spec.ParameterDefinitions, parameters[app.Spec.Name])
+		appParameters := bundleParameters(manifest.Spec.Parameters, parameters[app.Spec.Name], app.Spec.ParameterDefinitions)
+		pm := NewParameterManager(mm.kbClient, manifestRef.Namespace, app.Spec.Name, app.Spec.ParameterDefinitions, appParameters)
 		if suffixes[app.Spec.Name] != nil {
 			// Install once per suffix
 			for _, suffix := range suffixes[app.Spec.Name] {
@@ -155,36 +233,130 @@ func (mm *ManifestManager) Install(ctx context.Context, manifestRef ManifestRefe
 				}
 			}
 		} else {
-			_, err := mm.installMgr.Install(ctx, app.Spec.Name, app.Spec.Name, manifestRef.Namespace, app.Spec.Version, "", manifest.Spec.Flavor, dockerRegistry, force, parameters[app.Spec.Name])
+			_, err := mm.installMgr.Install(ctx, app.Spec.Name, app.Spec.Name, manifestRef.Namespace, app.Spec.Version, "", manifest.Spec.Flavor, dockerRegistry, force, appParameters)
 			if err != nil {
 				return errors.Wrapf(err, "couldn't install application %s", app.Spec.Name)
 			}
 		}
 	}
 
+	return mm.recordRequires(ctx, manifest, manifestRef.Namespace)
+}
+
+// recordRequires saves the dependencies of each install created from a manifest in its requires annotation. They
+// include the bundle requires of the manifest, such as postgres-auth, which the install's application doesn't list.
+func (mm *ManifestManager) recordRequires(ctx context.Context, manifest *v1alpha1.Manifest, namespace string) error {
+	graph, err := mm.resolveInstallGraph(ctx, manifest, namespace, false)
+	if err != nil {
+		return err
+	}
+
+	for name, install := range graph.installs {
+		b, err := json.Marshal(existingDependencies(graph.dependencies[name], graph.installs))
+		if err != nil {
+			return errors.Wrap(err, "couldn't encode requires")
+		}
+		if install.Annotations[requiresAnnotation] == string(b) {
+			continue
+		}
+
+		newInstall := install.DeepCopy()
+		if newInstall.Annotations == nil {
+			newInstall.Annotations = make(map[string]string)
+		}
+		newInstall.Annotations[requiresAnnotation] = string(b)
+		err = mm.resourceMgr.Patch(ctx, newInstall, install)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't record requires of install %q", name)
+		}
+	}
 	return nil
 }
 
-func (mm *ManifestManager) Deploy(ctx context.Context, manifestRef ManifestReference, showLogs bool, timeout time.Duration) error {
+// Deploy deploys all the bundles listed in this manifest
+func (mm *ManifestManager) Deploy(ctx context.Context, manifestRef ManifestReference, opts ManifestDeployOpts) ([]InstallResult, error) {
 	smoketest := false
-	return mm.deploy(ctx, manifestRef, showLogs, timeout, smoketest)
+	return mm.deploy(ctx, manifestRef, opts, smoketest)
 }
 
-// Deploy deploys all the bundles listed in this manifest
-func (mm *ManifestManager) DeploySmoketest(ctx context.Context, manifestRef ManifestReference, showLogs bool, timeout time.Duration) error {
+// DeploySmoketest deploys and smoketests all the bundles listed in this manifest
+func (mm *ManifestManager) DeploySmoketest(ctx context.Context, manifestRef ManifestReference, opts ManifestDeployOpts) ([]InstallResult, error) {
 	smoketest := true
-	return mm.deploy(ctx, manifestRef, showLogs, timeout, smoketest)
+	return mm.deploy(ctx, manifestRef, opts, smoketest)
 }
 
-func (mm *ManifestManager) deploy(ctx context.Context, manifestRef ManifestReference, showLogs bool, timeout time.Duration, smoketest bool) error {
-	var manifest v1alpha1.Manifest
-	err := mm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &manifest)
+func (mm *ManifestManager) deploy(ctx context.Context, manifestRef ManifestReference, opts ManifestDeployOpts, smoketest bool) ([]InstallResult, error) {
+	manifest, err := mm.getManifest(ctx, manifestRef)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
+		return nil, err
 	}
 
+	graph, err := mm.resolveInstallGraph(ctx, manifest, manifestRef.Namespace, false)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := manifestRef.Selection.selectInstalls(graph)
+	if err != nil {
+		return nil, err
+	}
+	layers := filterLayers(graph.layers, selected)
+
+	state := deployState{
+		manifestGeneration: manifest.Generation,
+		dependencies:       graph.dependencies,
+		unavailable:        make(map[string]bool),
+		redeployed:         make(map[string]bool),
+	}
+
+	// Process each layer in the determined order. Installs that failed, or were skipped, are unavailable to later layers.
+	var results []InstallResult
+	var deployErr error
+	for i, layer := range layers {
+		if len(layer) == 0 {
+			continue
+		}
+		log.WithFields(log.Fields{"level": i, "layer": layer}).Info("Processing layer")
+		halt := deployErr != nil && !opts.ContinueOnError
+		layerResults := mm.deployLayer(ctx, i, layer, graph, &state, opts, smoketest, halt)
+
+		for _, result := range layerResults {
+			switch result.Result {
+			case ResultSucceeded:
+				state.redeployed[result.Name] = true
+			case ResultFailed, ResultAllowedFailure, ResultSkipped:
+				state.unavailable[result.Name] = true
+			}
+			if result.Result == ResultFailed && deployErr == nil {
+				deployErr = errors.Wrapf(result.Err, "couldn't deploy '%s'", result.Name)
+			}
+		}
+		results = append(results, layerResults...)
+	}
+
+	return results, deployErr
+}
+
+// installGraph is the resolved dependency graph of the installs in a manifest, including suffixed installs
+type installGraph struct {
+	// layers lists install names in dependency order. Installs within a layer don't depend on each other.
+	layers [][]string
+
+	// installs maps install names to installs
+	installs map[string]*v1alpha1.Install
+
+	// bundles maps install names to the manifest bundle they were created from
+	bundles map[string]v1alpha1.BundleSpec
+
+	// dependencies maps each install name to the installs it requires
+	dependencies map[string][]string
+}
+
+// resolveInstallGraph builds the dependency graph of the installs created from a manifest. When ignoreMissing is set,
+// installs that don't exist are left out of the graph instead of causing an error.
+func (mm *ManifestManager) resolveInstallGraph(ctx context.Context, manifest *v1alpha1.Manifest, namespace string, ignoreMissing bool) (*installGraph, error) {
 	var entries []dependencysolver.Entry
 	installMap := make(map[string]*v1alpha1.Install)
+	bundleMap := make(map[string]v1alpha1.BundleSpec)
 
 	// Collect the suffixes to apply during deploy. The suffixes map describes which dependencies should be deployed with suffixes.
 	// For example, as a reusable bundle, postgres might be deployed for multiple services, each of which has a unique suffix.
@@ -236,18 +408,23 @@ func (mm *ManifestManager) deploy(ctx context.Context, manifestRef ManifestRefer
 			if suffix != "" {
 				installName += "-" + suffix
 			}
-			err := mm.resourceMgr.Get(ctx, installName, manifestRef.Namespace, &install)
+			err := mm.resourceMgr.Get(ctx, installName, namespace, &install)
 			if err != nil {
-				return errors.Wrapf(err, "couldn't get install for bundle '%s'", bundle.Name)
+				if ignoreMissing && apierrors.IsNotFound(errors.Cause(err)) {
+					log.WithFields(log.Fields{"install": installName}).Info("Install not found, ignoring")
+					continue
+				}
+				return nil, errors.Wrapf(err, "couldn't get install for bundle '%s'", bundle.Name)
 			}
 			installMap[installName] = &install
+			bundleMap[installName] = bundle
 
 			var app v1alpha1.Application
 			appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
 
-			err = mm.resourceMgr.Get(ctx, appName, manifestRef.Namespace, &app)
+			err = mm.resourceMgr.Get(ctx, appName, namespace, &app)
 			if err != nil {
-				return errors.Wrapf(err, "couldn't get application for bundle '%s'", bundle.Name)
+				return nil, errors.Wrapf(err, "couldn't get application for bundle '%s'", bundle.Name)
 			}
 
 			var deps []string
@@ -276,76 +453,319 @@ func (mm *ManifestManager) deploy(ctx context.Context, manifestRef ManifestRefer
 	}
 	log.WithFields(log.Fields{"entries": entries}).Debug("Assembled final solver entries")
 
+	// Drop dependencies on installs that no longer exist
+	if ignoreMissing {
+		for i, entry := range entries {
+			var deps []string
+			for _, dep := range entry.Deps {
+				if _, found := installMap[dep]; found {
+					deps = append(deps, dep)
+				}
+			}
+			entries[i].Deps = deps
+		}
+	}
+
 	// Resolve the dependency order
 	layers := dependencysolver.LayeredTopologicalSort(entries)
 	if layers == nil {
-		return errors.New("can't resolve dependencies; may be circular or have missing relationships")
+		return nil, errors.New("can't resolve dependencies; may be circular or have missing relationships")
 	}
 
-	// Process each layer in the determined order
-	for i, layer := range layers {
-		log.WithFields(log.Fields{"level": i, "layer": layer}).Info("Processing layer")
-		for _, name := range layer {
-			install := installMap[name]
+	graph := &installGraph{
+		layers:       layers,
+		installs:     installMap,
+		bundles:      bundleMap,
+		dependencies: make(map[string][]string, len(entries)),
+	}
+	for _, entry := range entries {
+		graph.dependencies[entry.ID] = entry.Deps
+	}
 
-			installRef := InstallReference{Name: install.Name, Namespace: install.Namespace}
-			if smoketest {
-				err := mm.deploySmoketestMgr.DeploySmoketest(ctx, installRef, showLogs, timeout)
-				if err != nil {
-					return errors.Wrapf(err, "couldn't deploy '%s'", install.Name)
+	return graph, nil
+}
+
+// deployState tracks installs across the layers of a manifest deploy. It is only modified between layers.
+type deployState struct {
+	manifestGeneration int64
+
+	// dependencies maps each install to the installs it requires
+	dependencies map[string][]string
+
+	// unavailable holds installs that failed or were skipped
+	unavailable map[string]bool
+
+	// redeployed holds installs that were deployed by this run, and may have changed their outputs
+	redeployed map[string]bool
+}
+
+// deployLayer deploys the installs in a single dependency layer, running up to opts.Parallelism of them at once. Unless
+// opts.ContinueOnError is set, a failure stops any installs in the layer that haven't started yet.
+func (mm *ManifestManager) deployLayer(ctx context.Context, level int, layer []string, graph *installGraph, state *deployState,
+	opts ManifestDeployOpts, smoketest bool, halt bool) []InstallResult {
+
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	baseOut := opts.Out
+	if baseOut == nil {
+		baseOut = os.Stdout
+	}
+
+	var outMu sync.Mutex
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+
+	results := make([]InstallResult, len(layer))
+	for i, name := range layer {
+		results[i] = InstallResult{Name: name, Layer: level, Result: ResultSkipped}
+
+		// Dependents of a redeployed install may see new outputs, so they can't be considered unchanged
+		resume := opts.Resume
+		for _, dep := range state.dependencies[name] {
+			if state.unavailable[dep] {
+				results[i].Err = errors.Errorf("dependency '%s' was not deployed", dep)
+				break
+			}
+			if state.redeployed[dep] {
+				resume = false
+			}
+		}
+		if results[i].Err != nil {
+			log.WithFields(log.Fields{"install": name, "err": results[i].Err}).Warn("Skipping install")
+			continue
+		}
+
+		sem <- struct{}{}
+		mu.Lock()
+		stopped := halt
+		mu.Unlock()
+		if stopped {
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, install *v1alpha1.Install, bundle v1alpha1.BundleSpec, resume bool) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			out := baseOut
+			if parallelism > 1 {
+				pw := NewPrefixWriter(baseOut, &outMu, fmt.Sprintf("[%s] ", install.Name))
+				defer pw.Flush()
+				out = pw
+			}
+
+			start := time.Now()
+			result, err := mm.deployInstall(ctx, install, bundle, state.manifestGeneration, opts, smoketest, resume, out)
+			results[i].Duration = time.Since(start)
+			results[i].Result = result
+			if err != nil {
+				results[i].Err = err
+				if bundle.AllowFailure {
+					results[i].Result = ResultAllowedFailure
+					log.WithFields(log.Fields{"install": install.Name, "err": err}).Warn("Deploy failed, failure is allowed")
+					return
 				}
-			} else {
-				deployOpts := DeployOpts{
-					Action:  ActionApplyOutputs,
-					Timeout: timeout,
+				log.WithFields(log.Fields{"install": install.Name, "err": err}).Error("Deploy failed")
+
+				if !opts.ContinueOnError {
+					mu.Lock()
+					halt = true
+					mu.Unlock()
 				}
-				err := mm.deployMgr.Deploy(ctx, installRef, deployOpts, showLogs)
-				if err != nil {
-					return errors.Wrapf(err, "couldn't deploy '%s'", install.Name)
+			}
+		}(i, graph.installs[name], graph.bundles[name], resume)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// deployInstall runs the deploy action, followed by smoketests if requested, for a single install. The timeout and retries
+// of the install's bundle override opts. When resuming, an install whose inputs match its last successful deploy is left alone.
+func (mm *ManifestManager) deployInstall(ctx context.Context, install *v1alpha1.Install, bundle v1alpha1.BundleSpec, manifestGeneration int64,
+	opts ManifestDeployOpts, smoketest bool, resume bool, out io.Writer) (string, error) {
+
+	installRef := InstallReference{Name: install.Name, Namespace: install.Namespace}
+	inputsHash, err := mm.deployMgr.InputsHash(ctx, installRef)
+	if err != nil {
+		return ResultFailed, errors.Wrapf(err, "couldn't hash inputs for '%s'", install.Name)
+	}
+
+	lastApplied := install.Status.LastApplied
+	if resume && lastApplied != nil && lastApplied.InputsHash == inputsHash {
+		fmt.Fprintf(out, "Skipping %s, unchanged since %v\n", install.Name, lastApplied.Time)
+		return ResultUnchanged, nil
+	}
+
+	deployOpts := DeployOpts{
+		Action:  ActionApplyOutputs,
+		Timeout: opts.Timeout,
+		Out:     out,
+		Retries: bundle.Retries,
+	}
+	if bundle.Timeout != nil && bundle.Timeout.Duration > 0 {
+		deployOpts.Timeout = bundle.Timeout.Duration
+	}
+	if bundle.RetryBackoff != nil {
+		deployOpts.RetryBackoff = bundle.RetryBackoff.Duration
+	}
+	err = mm.deployMgr.Deploy(ctx, installRef, deployOpts, opts.ShowLogs)
+	if err != nil {
+		return ResultFailed, errors.Wrapf(err, "couldn't execute deploy for '%s'", install.Name)
+	}
+
+	if smoketest {
+		deployOpts.Action = ActionSmoketest
+		err = mm.deployMgr.Deploy(ctx, installRef, deployOpts, opts.ShowLogs)
+		if err != nil {
+			return ResultFailed, errors.Wrapf(err, "smoketest failed for '%s'", install.Name)
+		}
+	}
+
+	err = mm.deployMgr.RecordApplied(ctx, installRef, manifestGeneration, inputsHash)
+	if err != nil {
+		return ResultFailed, err
+	}
+
+	return ResultSucceeded, nil
+}
+
+// Uninstall runs the delete action for every install created from this manifest in reverse dependency order, so that
+// installs are removed before the installs they require. Each Install is removed once its delete action succeeds. Unless
+// opts.Force is set, uninstall stops at the first failed delete action.
+func (mm *ManifestManager) Uninstall(ctx context.Context, manifestRef ManifestReference, opts ManifestUninstallOpts) ([]InstallResult, error) {
+	manifest, err := mm.getManifest(ctx, manifestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Installs removed by an earlier, interrupted uninstall are ignored
+	graph, err := mm.resolveInstallGraph(ctx, manifest, manifestRef.Namespace, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []InstallResult
+	var uninstallErr error
+	appNames := make(map[string]bool)
+	for i := len(graph.layers) - 1; i >= 0; i-- {
+		log.WithFields(log.Fields{"level": i, "layer": graph.layers[i]}).Info("Processing layer")
+		for _, name := range graph.layers[i] {
+			result := InstallResult{Name: name, Layer: i, Result: ResultSkipped}
+			if uninstallErr != nil {
+				results = append(results, result)
+				continue
+			}
+
+			install := graph.installs[name]
+			installRef := InstallReference{Name: install.Name, Namespace: install.Namespace}
+			start := time.Now()
+
+			// Run the deploy container with action=delete
+			deployOpts := DeployOpts{
+				Action:  ActionDelete,
+				Timeout: opts.Timeout,
+			}
+			err := mm.deployMgr.Deploy(ctx, installRef, deployOpts, opts.ShowLogs)
+			if err != nil {
+				result.Result = ResultFailed
+				result.Err = err
+				result.Duration = time.Since(start)
+				results = append(results, result)
+				if !opts.Force {
+					uninstallErr = errors.Wrapf(err, "couldn't execute delete for '%s'", name)
+					continue
 				}
+				log.WithFields(log.Fields{"err": err, "install": name}).Error("couldn't execute delete, continuing anyway")
+			}
+
+			// Delete the install
+			err = mm.deployMgr.Delete(ctx, installRef)
+			if err != nil {
+				return results, errors.Wrapf(err, "couldn't delete install for '%s'", name)
+			}
+			appNames[fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)] = true
+
+			if result.Result != ResultFailed {
+				result.Result = ResultSucceeded
+				result.Duration = time.Since(start)
+				results = append(results, result)
 			}
 		}
 	}
+	if uninstallErr != nil {
+		return results, uninstallErr
+	}
 
-	return nil
+	if opts.Unregister {
+		err = mm.unregisterApplications(ctx, appNames, manifestRef.Namespace)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
 }
 
-func (mm *ManifestManager) Diff(ctx context.Context, manifestRef ManifestReference, timeout time.Duration) error {
-	var manifest v1alpha1.Manifest
-	err := mm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &manifest)
+// unregisterApplications removes the named Applications, unless they are still used by a remaining Install
+func (mm *ManifestManager) unregisterApplications(ctx context.Context, appNames map[string]bool, namespace string) error {
+	var installs v1alpha1.InstallList
+	err := mm.resourceMgr.List(ctx, namespace, &installs)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
+		return errors.Wrap(err, "couldn't list installs")
 	}
 
-	for _, bundle := range manifest.Spec.Bundles {
-		installRef := InstallReference{Name: bundle.Name, Namespace: manifestRef.Namespace}
-		deployOpts := DeployOpts{
-			Action:  ActionDiff,
-			Timeout: timeout,
+	inUse := make(map[string]bool)
+	for _, install := range installs.Items {
+		inUse[fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)] = true
+	}
+
+	for appName := range appNames {
+		if inUse[appName] {
+			log.WithFields(log.Fields{"application": appName}).Info("Application still in use, not unregistering")
+			continue
 		}
 
-		err := mm.deployMgr.Deploy(ctx, installRef, deployOpts, true)
+		var app v1alpha1.Application
+		err := mm.resourceMgr.Delete(ctx, appName, namespace, &app)
 		if err != nil {
-			return errors.Wrapf(err, "couldn't diff '%s'", bundle.Name)
+			return errors.Wrapf(err, "couldn't unregister application %q", appName)
 		}
+		log.WithFields(log.Fields{"application": appName}).Info("Application unregistered")
 	}
 
 	return nil
 }
 
-// Smoketest smoketests all the bundles listed in this manifest
+// Smoketest smoketests all the installs created from this manifest, including suffixed installs, in dependency order
 func (mm *ManifestManager) Smoketest(ctx context.Context, manifestRef ManifestReference, showLogs bool, timeout time.Duration) error {
-	var manifest v1alpha1.Manifest
-	err := mm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &manifest)
+	manifest, err := mm.getManifest(ctx, manifestRef)
 	if err != nil {
-		return errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
+		return err
 	}
 
-	for _, bundle := range manifest.Spec.Bundles {
-		installRef := InstallReference{Name: bundle.Name, Namespace: manifestRef.Namespace}
-		err := mm.smoketestMgr.Smoketest(ctx, installRef, showLogs, timeout)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't deploy '%s'", bundle.Name)
+	graph, err := mm.resolveInstallGraph(ctx, manifest, manifestRef.Namespace, false)
+	if err != nil {
+		return err
+	}
+	selected, err := manifestRef.Selection.selectInstalls(graph)
+	if err != nil {
+		return err
+	}
+
+	for _, layer := range filterLayers(graph.layers, selected) {
+		for _, name := range layer {
+			installRef := InstallReference{Name: name, Namespace: manifestRef.Namespace}
+			err := mm.smoketestMgr.Smoketest(ctx, installRef, showLogs, timeout)
+			if err != nil {
+				return errors.Wrapf(err, "couldn't smoketest '%s'", name)
+			}
 		}
 	}
 
@@ -0,0 +1,197 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestLock pins the bundles of a manifest to the exact versions and contents resolved from its sources
+type ManifestLock struct {
+	Manifest string         `json:"manifest"`
+	Bundles  []LockedBundle `json:"bundles"`
+}
+
+type LockedBundle struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Digest is the sha256 digest of the bundle file, as "sha256:<hex>"
+	Digest string `json:"digest"`
+}
+
+// LoadManifestLock reads a lockfile written by WriteManifestLock
+func LoadManifestLock(filename string) (*ManifestLock, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open lockfile %q", filename)
+	}
+	defer f.Close()
+
+	var lock ManifestLock
+	decoder := k8syaml.NewYAMLOrJSONDecoder(f, 100)
+	err = decoder.Decode(&lock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't decode lockfile %q", filename)
+	}
+	return &lock, nil
+}
+
+// WriteManifestLock writes a lockfile as yaml
+func WriteManifestLock(filename string, lock *ManifestLock) error {
+	b, err := yaml.Marshal(lock)
+	if err != nil {
+		return errors.Wrap(err, "couldn't encode lockfile")
+	}
+	err = os.WriteFile(filename, b, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't write lockfile %q", filename)
+	}
+	return nil
+}
+
+func (l *ManifestLock) get(name string) (LockedBundle, bool) {
+	for _, bundle := range l.Bundles {
+		if bundle.Name == name {
+			return bundle, true
+		}
+	}
+	return LockedBundle{}, false
+}
+
+// Lock resolves every bundle of a manifest from its sources and returns their versions and digests
+func (mm *ManifestManager) Lock(ctx context.Context, manifestRef ManifestReference) (*ManifestLock, error) {
+	manifest, err := mm.getManifest(ctx, manifestRef)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := getManifestSource(ctx, mm.resourceMgr, manifest, manifestRef.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &ManifestLock{Manifest: manifest.Name}
+	for _, bundle := range manifest.Spec.Bundles {
+		bundleRef := BundleRef{Name: bundle.Name, Version: bundle.Version}
+		bundleFile, err := source.Get(bundleRef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get bundle '%s'", bundleRef.Filename())
+		}
+
+		digest, err := bundleDigest(bundleFile)
+		_ = bundleFile.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get digest of bundle '%s'", bundleFile)
+		}
+
+		log.WithFields(log.Fields{"bundle": bundle.Name, "version": bundleFile.Version, "digest": digest}).Info("Locked bundle")
+		lock.Bundles = append(lock.Bundles, LockedBundle{
+			Name:    bundle.Name,
+			Version: bundleFile.Version,
+			Digest:  digest,
+		})
+	}
+
+	return lock, nil
+}
+
+// getManifestSource returns a source that searches the sources of a manifest in order
+func getManifestSource(ctx context.Context, resourceMgr *ResourceManager, manifest *v1alpha1.Manifest, namespace string) (Source, error) {
+	var sources []Source
+	for _, sourceInfo := range manifest.Spec.Sources {
+		var src v1alpha1.Source
+		err := resourceMgr.Get(ctx, sourceInfo.Name, namespace, &src)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get source '%s'", sourceInfo.Name)
+		}
+
+		newSource, err := NewSource(src.Spec.Type, src.Spec.Path, src.Spec.Options, sourceInfo.Section, sourceInfo.Release)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't create new source")
+		}
+		sources = append(sources, newSource)
+	}
+	return NewMultiSource(sources), nil
+}
+
+// bundleDigest returns the sha256 digest of a bundle file's contents
+func bundleDigest(bundleFile *BundleFile) (string, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, io.NewSectionReader(bundleFile.Contents, 0, bundleFile.Size))
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewLockedSource returns a source that only returns the bundle versions and contents recorded in a lockfile. Bundles
+// that aren't in the lockfile, or whose version or digest doesn't match it, are refused.
+func NewLockedSource(source Source, lock *ManifestLock) Source {
+	return &LockedSource{
+		source: source,
+		lock:   lock,
+	}
+}
+
+type LockedSource struct {
+	source Source
+	lock   *ManifestLock
+}
+
+func (ls *LockedSource) Get(bundleRef BundleRef) (*BundleFile, error) {
+	locked, found := ls.lock.get(bundleRef.Name)
+	if !found {
+		return nil, fmt.Errorf("bundle '%s' isn't in the lockfile", bundleRef.Name)
+	}
+	if bundleRef.Version != Latest && bundleRef.Version != locked.Version {
+		return nil, fmt.Errorf("bundle '%s' version '%s' doesn't match locked version '%s'", bundleRef.Name, bundleRef.Version, locked.Version)
+	}
+
+	bundleRef.Version = locked.Version
+	bundleFile, err := ls.source.Get(bundleRef)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := bundleDigest(bundleFile)
+	if err != nil {
+		_ = bundleFile.Close()
+		return nil, errors.Wrapf(err, "couldn't get digest of bundle '%s'", bundleFile)
+	}
+	if digest != locked.Digest {
+		_ = bundleFile.Close()
+		return nil, fmt.Errorf("bundle '%s' digest %s doesn't match locked digest %s", bundleFile, digest, locked.Digest)
+	}
+
+	return bundleFile, nil
+}
+
+func (ls *LockedSource) Put(bundleFile *BundleFile) error {
+	return ls.source.Put(bundleFile)
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBundle(t *testing.T, dir, name, version, description string) {
+	t.Helper()
+	appYAML := "spec:\n  name: " + name + "\n  version: " + version + "\n  description: " + description + "\n"
+	writeBundleFile(t, filepath.Join(dir, name+"-"+version+".kb"), appYAML)
+}
+
+// writeBundleFile writes a bundle containing only an app.yaml
+func writeBundleFile(t *testing.T, filename, appYAML string) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	appFile, err := w.Create(DefaultAppFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = appFile.Write([]byte(appYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockedSource(t *testing.T) {
+	dir := t.TempDir()
+	writeBundle(t, dir, "nginx", "1.0.0", "original")
+	source := &DirectorySource{Path: dir}
+
+	bundleFile, err := source.Get(BundleRef{Name: "nginx", Version: "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := bundleDigest(bundleFile)
+	bundleFile.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock := &ManifestLock{Bundles: []LockedBundle{{Name: "nginx", Version: "1.0.0", Digest: digest}}}
+	lockedSource := NewLockedSource(source, lock)
+
+	// latest resolves to the locked version
+	bundleFile, err = lockedSource.Get(BundleRef{Name: "nginx", Version: Latest})
+	if err != nil {
+		t.Fatalf("expected locked bundle, got %v", err)
+	}
+	if bundleFile.Version != "1.0.0" {
+		t.Errorf("expected version 1.0.0, got %s", bundleFile.Version)
+	}
+	bundleFile.Close()
+
+	_, err = lockedSource.Get(BundleRef{Name: "nginx", Version: "1.1.0"})
+	if err == nil || !strings.Contains(err.Error(), "doesn't match locked version") {
+		t.Errorf("expected version mismatch, got %v", err)
+	}
+
+	_, err = lockedSource.Get(BundleRef{Name: "postgres", Version: Latest})
+	if err == nil || !strings.Contains(err.Error(), "isn't in the lockfile") {
+		t.Errorf("expected unlocked bundle to be refused, got %v", err)
+	}
+
+	// A bundle republished with the same version is refused
+	writeBundle(t, dir, "nginx", "1.0.0", "republished")
+	_, err = lockedSource.Get(BundleRef{Name: "nginx", Version: "1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "doesn't match locked digest") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LoadManifestFile reads a manifest from a yaml or json file
+func LoadManifestFile(filename string) (*v1alpha1.Manifest, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't open manifest file %q", filename)
+	}
+	defer f.Close()
+
+	var manifest v1alpha1.Manifest
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 100)
+	err = decoder.Decode(&manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't decode manifest file %q", filename)
+	}
+	return &manifest, nil
+}
+
+// MergeManifests returns a copy of the base manifest with the overlays merged over it, in order. A later overlay takes
+// precedence over earlier ones and over the base:
+//
+//   - flavor, registry, cpu and memory are replaced when set
+//   - sources and manifest-wide parameters are merged by name
+//   - bundles are merged by name, and bundles not in the base are added. Within a bundle, the version, timeout, retries
+//     and retry backoff are replaced when set, requires are replaced when any are given, and parameters are merged by
+//     name.
+func MergeManifests(base *v1alpha1.Manifest, overlays ...*v1alpha1.Manifest) *v1alpha1.Manifest {
+	merged := base.DeepCopy()
+	for _, overlay := range overlays {
+		spec := &merged.Spec
+		if overlay.Spec.Flavor != "" {
+			spec.Flavor = overlay.Spec.Flavor
+		}
+		if overlay.Spec.Registry != "" {
+			spec.Registry = overlay.Spec.Registry
+		}
+		if overlay.Spec.CPU != "" {
+			spec.CPU = overlay.Spec.CPU
+		}
+		if overlay.Spec.Memory != "" {
+			spec.Memory = overlay.Spec.Memory
+		}
+
+		for _, source := range overlay.Spec.Sources {
+			spec.Sources = mergeSource(spec.Sources, source)
+		}
+		spec.Parameters = mergeParameters(spec.Parameters, overlay.Spec.Parameters)
+
+		for _, bundle := range overlay.Spec.Bundles {
+			spec.Bundles = mergeBundle(spec.Bundles, *bundle.DeepCopy())
+		}
+	}
+	return merged
+}
+
+func mergeSource(sources []v1alpha1.SourceInfo, source v1alpha1.SourceInfo) []v1alpha1.SourceInfo {
+	for i := range sources {
+		if sources[i].Name == source.Name {
+			sources[i] = source
+			return sources
+		}
+	}
+	return append(sources, source)
+}
+
+func mergeBundle(bundles []v1alpha1.BundleSpec, bundle v1alpha1.BundleSpec) []v1alpha1.BundleSpec {
+	for i := range bundles {
+		if bundles[i].Name != bundle.Name {
+			continue
+		}
+
+		merged := &bundles[i]
+		if bundle.Version != "" {
+			merged.Version = bundle.Version
+		}
+		if len(bundle.Requires) > 0 {
+			merged.Requires = bundle.Requires
+		}
+		if bundle.Timeout != nil {
+			merged.Timeout = bundle.Timeout
+		}
+		if bundle.Retries != 0 {
+			merged.Retries = bundle.Retries
+		}
+		if bundle.RetryBackoff != nil {
+			merged.RetryBackoff = bundle.RetryBackoff
+		}
+		if bundle.AllowFailure {
+			merged.AllowFailure = true
+		}
+		merged.Parameters = mergeParameters(merged.Parameters, bundle.Parameters)
+		return bundles
+	}
+	return append(bundles, bundle)
+}
+
+// mergeParameters returns the parameters with the overrides set over them by name
+func mergeParameters(parameters, overrides []v1alpha1.ParameterSpec) []v1alpha1.ParameterSpec {
+	for _, override := range overrides {
+		found := false
+		for i := range parameters {
+			if parameters[i].Name == override.Name {
+				parameters[i] = override
+				found = true
+				break
+			}
+		}
+		if !found {
+			parameters = append(parameters, override)
+		}
+	}
+	return parameters
+}
+
+// bundleParameters returns the parameters of a bundle, followed by the manifest-wide parameters the application defines
+// that the bundle doesn't set
+func bundleParameters(manifestParameters, parameters []v1alpha1.ParameterSpec, definitions []v1alpha1.ParameterDefinitionSpec) []v1alpha1.ParameterSpec {
+	result := append([]v1alpha1.ParameterSpec{}, parameters...)
+	for _, parameter := range manifestParameters {
+		if !hasDefinition(definitions, parameter.Name) {
+			continue
+		}
+		found := false
+		for _, p := range parameters {
+			if p.Name == parameter.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, parameter)
+		}
+	}
+	return result
+}
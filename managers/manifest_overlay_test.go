@@ -0,0 +1,91 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+func TestMergeManifests(t *testing.T) {
+	base := &v1alpha1.Manifest{
+		Spec: v1alpha1.ManifestSpec{
+			Flavor:     "single-node",
+			Sources:    []v1alpha1.SourceInfo{{Name: "local"}},
+			Parameters: []v1alpha1.ParameterSpec{{Name: "domain", Value: "dev.example.com"}, {Name: "log_level", Value: "debug"}},
+			Bundles: []v1alpha1.BundleSpec{
+				{Name: "nginx", Version: "1.0.0", Parameters: []v1alpha1.ParameterSpec{{Name: "replicas", Value: "1"}}},
+				{Name: "postgres", Version: "13.0.0"},
+			},
+		},
+	}
+	stage := &v1alpha1.Manifest{
+		Spec: v1alpha1.ManifestSpec{
+			Flavor:     "three-node",
+			Parameters: []v1alpha1.ParameterSpec{{Name: "domain", Value: "stage.example.com"}},
+			Bundles: []v1alpha1.BundleSpec{
+				{Name: "nginx", Parameters: []v1alpha1.ParameterSpec{{Name: "replicas", Value: "2"}}},
+			},
+		},
+	}
+	prod := &v1alpha1.Manifest{
+		Spec: v1alpha1.ManifestSpec{
+			Sources:    []v1alpha1.SourceInfo{{Name: "local", Release: "stable"}},
+			Parameters: []v1alpha1.ParameterSpec{{Name: "domain", Value: "example.com"}},
+			Bundles: []v1alpha1.BundleSpec{
+				{Name: "nginx", Version: "1.1.0", Parameters: []v1alpha1.ParameterSpec{{Name: "replicas", Value: "3"}}},
+				{Name: "redis", Version: "7.0.0"},
+			},
+		},
+	}
+
+	merged := MergeManifests(base, stage, prod)
+
+	expected := v1alpha1.ManifestSpec{
+		Flavor:     "three-node",
+		Sources:    []v1alpha1.SourceInfo{{Name: "local", Release: "stable"}},
+		Parameters: []v1alpha1.ParameterSpec{{Name: "domain", Value: "example.com"}, {Name: "log_level", Value: "debug"}},
+		Bundles: []v1alpha1.BundleSpec{
+			{Name: "nginx", Version: "1.1.0", Parameters: []v1alpha1.ParameterSpec{{Name: "replicas", Value: "3"}}},
+			{Name: "postgres", Version: "13.0.0"},
+			{Name: "redis", Version: "7.0.0"},
+		},
+	}
+	if !reflect.DeepEqual(merged.Spec, expected) {
+		t.Errorf("expected %+v, got %+v", expected, merged.Spec)
+	}
+
+	// The base isn't modified
+	if base.Spec.Bundles[0].Version != "1.0.0" || base.Spec.Parameters[0].Value != "dev.example.com" {
+		t.Errorf("base manifest was modified: %+v", base.Spec)
+	}
+}
+
+func TestBundleParameters(t *testing.T) {
+	manifestParameters := []v1alpha1.ParameterSpec{{Name: "domain", Value: "example.com"}, {Name: "storage_class", Value: "fast"}, {Name: "log_level", Value: "info"}}
+	parameters := []v1alpha1.ParameterSpec{{Name: "log_level", Value: "debug"}}
+	definitions := []v1alpha1.ParameterDefinitionSpec{{Name: "domain"}, {Name: "log_level"}}
+
+	result := bundleParameters(manifestParameters, parameters, definitions)
+
+	expected := []v1alpha1.ParameterSpec{{Name: "log_level", Value: "debug"}, {Name: "domain", Value: "example.com"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeployLayer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clientgoscheme.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Installs of the web application are unchanged since their last deploy, so resuming doesn't run a job. The broken
+	// install's application isn't registered, so its deploy fails before a job is created.
+	flavor := &v1alpha1.Flavor{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1.0.0", Namespace: "default"},
+		Spec:       v1alpha1.ApplicationSpec{Name: "web", Version: "1.0.0"},
+	}
+	objs := []runtime.Object{flavor, app}
+	for _, name := range []string{"web-a", "web-b", "web-c"} {
+		objs = append(objs, &v1alpha1.Install{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1alpha1.InstallSpec{Application: "web", Version: "1.0.0", Flavor: "default"},
+		})
+	}
+	objs = append(objs, &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "missing", Version: "1.0.0", Flavor: "default"},
+	})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	mm := NewManifestManager(KBClient{Client: c})
+
+	installs := make(map[string]*v1alpha1.Install)
+	for _, name := range []string{"web-a", "web-b", "web-c", "broken"} {
+		var install v1alpha1.Install
+		err := mm.resourceMgr.Get(context.Background(), name, "default", &install)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "broken" {
+			hash, err := mm.deployMgr.InputsHash(context.Background(), InstallReference{Name: name, Namespace: "default"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			install.Status.LastApplied = &v1alpha1.LastAppliedStatus{Version: "1.0.0", InputsHash: hash}
+		}
+		installs[name] = &install
+	}
+
+	tests := []struct {
+		name         string
+		layer        []string
+		bundles      map[string]v1alpha1.BundleSpec
+		dependencies map[string][]string
+		unavailable  map[string]bool
+		opts         ManifestDeployOpts
+		halt         bool
+		expected     []string
+	}{
+		{
+			name:     "parallel unchanged installs",
+			layer:    []string{"web-a", "web-b", "web-c"},
+			opts:     ManifestDeployOpts{Parallelism: 3, Resume: true},
+			expected: []string{ResultUnchanged, ResultUnchanged, ResultUnchanged},
+		},
+		{
+			name:         "unavailable dependency",
+			layer:        []string{"web-a", "web-b"},
+			dependencies: map[string][]string{"web-b": {"db"}},
+			unavailable:  map[string]bool{"db": true},
+			opts:         ManifestDeployOpts{Parallelism: 2, Resume: true},
+			expected:     []string{ResultUnchanged, ResultSkipped},
+		},
+		{
+			name:     "failure stops the rest of the layer",
+			layer:    []string{"broken", "web-a"},
+			opts:     ManifestDeployOpts{Parallelism: 1, Resume: true},
+			expected: []string{ResultFailed, ResultSkipped},
+		},
+		{
+			name:     "continue on error",
+			layer:    []string{"broken", "web-a"},
+			opts:     ManifestDeployOpts{Parallelism: 1, Resume: true, ContinueOnError: true},
+			expected: []string{ResultFailed, ResultUnchanged},
+		},
+		{
+			name:     "allowed failure",
+			layer:    []string{"broken", "web-a"},
+			bundles:  map[string]v1alpha1.BundleSpec{"broken": {Name: "broken", AllowFailure: true}},
+			opts:     ManifestDeployOpts{Parallelism: 1, Resume: true},
+			expected: []string{ResultAllowedFailure, ResultUnchanged},
+		},
+		{
+			name:     "halted by an earlier layer",
+			layer:    []string{"web-a", "web-b"},
+			opts:     ManifestDeployOpts{Parallelism: 2, Resume: true},
+			halt:     true,
+			expected: []string{ResultSkipped, ResultSkipped},
+		},
+	}
+
+	for _, test := range tests {
+		graph := &installGraph{installs: installs, bundles: test.bundles}
+		state := &deployState{
+			dependencies: test.dependencies,
+			unavailable:  test.unavailable,
+			redeployed:   make(map[string]bool),
+		}
+		var out bytes.Buffer
+		test.opts.Out = &out
+
+		results := mm.deployLayer(context.Background(), 1, test.layer, graph, state, test.opts, false, test.halt)
+		if len(results) != len(test.expected) {
+			t.Fatalf("%s: expected %d results, got %d", test.name, len(test.expected), len(results))
+		}
+		for i, result := range results {
+			if result.Name != test.layer[i] || result.Layer != 1 || result.Result != test.expected[i] {
+				t.Errorf("%s: expected %s to be %s in layer 1, got %s in layer %d (%v)", test.name, test.layer[i], test.expected[i], result.Result, result.Layer, result.Err)
+			}
+		}
+	}
+}
+
+func TestUninstallOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ManifestUninstallOpts
+		expected []InstallResult
+		wantErr  bool
+		remains  []string
+	}{
+		{
+			name: "stop at the first failure",
+			expected: []InstallResult{
+				{Name: "web", Layer: 1, Result: ResultFailed},
+				{Name: "db", Layer: 0, Result: ResultSkipped},
+			},
+			wantErr: true,
+			remains: []string{"db", "web"},
+		},
+		{
+			name: "force",
+			opts: ManifestUninstallOpts{Force: true},
+			expected: []InstallResult{
+				{Name: "web", Layer: 1, Result: ResultFailed},
+				{Name: "db", Layer: 0, Result: ResultFailed},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		scheme := runtime.NewScheme()
+		err := v1alpha1.AddToScheme(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = clientgoscheme.AddToScheme(scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The installs use a flavor that doesn't exist, so their delete actions fail before a job is created
+		manifest := &v1alpha1.Manifest{
+			ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "default"},
+			Spec: v1alpha1.ManifestSpec{
+				Bundles: []v1alpha1.BundleSpec{{Name: "db", Version: "1.0.0"}, {Name: "web", Version: "1.0.0"}},
+			},
+		}
+		dbApp := &v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-1.0.0", Namespace: "default"},
+			Spec:       v1alpha1.ApplicationSpec{Name: "db", Version: "1.0.0"},
+		}
+		webApp := &v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1.0.0", Namespace: "default"},
+			Spec:       v1alpha1.ApplicationSpec{Name: "web", Version: "1.0.0", Requires: []v1alpha1.RequiresList{{Name: "db"}}},
+		}
+		db := &v1alpha1.Install{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       v1alpha1.InstallSpec{Application: "db", Version: "1.0.0", Flavor: "missing"},
+		}
+		web := &v1alpha1.Install{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1alpha1.InstallSpec{Application: "web", Version: "1.0.0", Flavor: "missing"},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(manifest, dbApp, webApp, db, web).Build()
+		mm := NewManifestManager(KBClient{Client: c})
+
+		results, err := mm.Uninstall(context.Background(), ManifestReference{Name: "stack", Namespace: "default"}, test.opts)
+		if test.wantErr && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+
+		if len(results) != len(test.expected) {
+			t.Fatalf("%s: expected %d results, got %d", test.name, len(test.expected), len(results))
+		}
+		for i, result := range results {
+			expected := test.expected[i]
+			if result.Name != expected.Name || result.Layer != expected.Layer || result.Result != expected.Result {
+				t.Errorf("%s: expected %s in layer %d to be %s, got %s in layer %d %s", test.name, expected.Name, expected.Layer, expected.Result, result.Name, result.Layer, result.Result)
+			}
+		}
+
+		var installs v1alpha1.InstallList
+		err = c.List(context.Background(), &installs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var remains []string
+		for _, install := range installs.Items {
+			remains = append(remains, install.Name)
+		}
+		if strings.Join(remains, ",") != strings.Join(test.remains, ",") {
+			t.Errorf("%s: expected installs %v to remain, got %v", test.name, test.remains, remains)
+		}
+	}
+}
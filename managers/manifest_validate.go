@@ -0,0 +1,375 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	yamlv3 "gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ManifestProblem is a problem found in a manifest. Path locates the field with the problem, such as
+// spec.bundles[1].parameters[0].name. Line and Column are set when the manifest was read from a file.
+type ManifestProblem struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p ManifestProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// manifestValidation collects the problems found in a manifest
+type manifestValidation struct {
+	problems []ManifestProblem
+}
+
+func (v *manifestValidation) addf(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, ManifestProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateFile validates a manifest file, and locates each problem in the file
+func (mm *ManifestManager) ValidateFile(ctx context.Context, filename, namespace string) ([]ManifestProblem, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read manifest file %q", filename)
+	}
+
+	var manifest v1alpha1.Manifest
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 100)
+	err = decoder.Decode(&manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't decode manifest file %q", filename)
+	}
+
+	problems, err := mm.Validate(ctx, &manifest, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yamlv3.Node
+	err = yamlv3.Unmarshal(data, &root)
+	if err != nil {
+		// json manifests are valid yaml, so this is unexpected; report the problems without locations
+		return problems, nil
+	}
+	for i := range problems {
+		node := locateYAMLPath(&root, problems[i].Path)
+		if node != nil {
+			problems[i].Line = node.Line
+			problems[i].Column = node.Column
+		}
+	}
+	return problems, nil
+}
+
+// Validate checks a manifest against its sources and the applications of its bundles, and returns every problem found:
+// missing sources, unknown bundles, parameters the applications don't define, missing required parameters, requires
+// without a matching bundle, suffixes that collide with other installs, and dependency cycles. An error is only
+// returned if the manifest couldn't be checked.
+func (mm *ManifestManager) Validate(ctx context.Context, manifest *v1alpha1.Manifest, namespace string) ([]ManifestProblem, error) {
+	v := &manifestValidation{}
+
+	// Sources
+	var sources []Source
+	if len(manifest.Spec.Sources) == 0 {
+		v.addf("spec.sources", "no sources")
+	}
+	for i, sourceInfo := range manifest.Spec.Sources {
+		path := fmt.Sprintf("spec.sources[%d].name", i)
+		var src v1alpha1.Source
+		err := mm.resourceMgr.Get(ctx, sourceInfo.Name, namespace, &src)
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			v.addf(path, "source '%s' not found", sourceInfo.Name)
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get source '%s'", sourceInfo.Name)
+		}
+
+		newSource, err := NewSource(src.Spec.Type, src.Spec.Path, src.Spec.Options, sourceInfo.Section, sourceInfo.Release)
+		if err != nil {
+			v.addf(path, "%v", err)
+			continue
+		}
+		sources = append(sources, newSource)
+	}
+	multiSource := NewMultiSource(sources)
+
+	// Bundles and their applications
+	apps := make(map[string]*v1alpha1.Application)
+	bundleIndex := make(map[string]int)
+	providers := make(map[string]string)
+	for i, bundle := range manifest.Spec.Bundles {
+		path := fmt.Sprintf("spec.bundles[%d]", i)
+		if j, found := bundleIndex[bundle.Name]; found {
+			v.addf(path+".name", "bundle '%s' is also listed at spec.bundles[%d]", bundle.Name, j)
+			continue
+		}
+		bundleIndex[bundle.Name] = i
+
+		if bundle.Timeout != nil && bundle.Timeout.Duration <= 0 {
+			v.addf(path+".timeout", "timeout must be positive")
+		}
+		if bundle.Retries < 0 {
+			v.addf(path+".retries", "retries must not be negative")
+		}
+		if bundle.RetryBackoff != nil && bundle.RetryBackoff.Duration < 0 {
+			v.addf(path+".retryBackoff", "retryBackoff must not be negative")
+		}
+
+		bundleRef := BundleRef{Name: bundle.Name, Version: bundle.Version}
+		bundleFile, err := multiSource.Get(bundleRef)
+		if err == ErrNotFound {
+			v.addf(path+".name", "bundle '%s' version '%s' not found in sources", bundle.Name, bundle.Version)
+			continue
+		} else if err != nil {
+			v.addf(path+".name", "couldn't get bundle '%s': %v", bundleRef.Filename(), err)
+			continue
+		}
+		app, err := bundleFile.Application(namespace)
+		_ = bundleFile.Close()
+		if err != nil {
+			v.addf(path+".name", "couldn't read application of bundle '%s': %v", bundle.Name, err)
+			continue
+		}
+		apps[bundle.Name] = app
+		for _, provides := range app.Spec.Provides {
+			providers[provides.Name] = bundle.Name
+		}
+	}
+
+	// Manifest-wide parameters must be defined by at least one application
+	for j, parameter := range manifest.Spec.Parameters {
+		defined := false
+		for _, app := range apps {
+			if hasDefinition(app.Spec.ParameterDefinitions, parameter.Name) {
+				defined = true
+				break
+			}
+		}
+		if !defined && len(apps) == len(bundleIndex) {
+			v.addf(fmt.Sprintf("spec.parameters[%d].name", j), "parameter '%s' isn't defined by any application", parameter.Name)
+		}
+	}
+
+	// origins maps each install name to the bundle and suffix it's created from, to find collisions
+	origins := make(map[string]string)
+	for _, bundle := range manifest.Spec.Bundles {
+		if _, found := origins[bundle.Name]; !found {
+			origins[bundle.Name] = fmt.Sprintf("bundle '%s'", bundle.Name)
+		}
+	}
+
+	// dependencies maps each bundle to the bundles it requires
+	dependencies := make(map[string][]string)
+	for i, bundle := range manifest.Spec.Bundles {
+		path := fmt.Sprintf("spec.bundles[%d]", i)
+		if bundleIndex[bundle.Name] != i {
+			continue
+		}
+		app := apps[bundle.Name]
+
+		if app != nil {
+			mm.validateBundleParameters(v, path, bundle, app, manifest.Spec.Parameters)
+
+			for _, require := range app.Spec.Requires {
+				provider, found := providers[require.Name]
+				if !found {
+					v.addf(path+".name", "application '%s' requires '%s', which no bundle in the manifest provides", bundle.Name, require.Name)
+					continue
+				}
+				dependencies[bundle.Name] = appendUnique(dependencies[bundle.Name], provider)
+			}
+		}
+
+		for k, require := range bundle.Requires {
+			requirePath := fmt.Sprintf("%s.requires[%d]", path, k)
+			_, found := bundleIndex[require.Name]
+			if !found {
+				v.addf(requirePath+".name", "requires '%s', which isn't a bundle in the manifest", require.Name)
+				continue
+			}
+			dependencies[bundle.Name] = appendUnique(dependencies[bundle.Name], require.Name)
+
+			if require.Suffix != "" {
+				installName := getResourceName(require.Name, require.Suffix)
+				origin := fmt.Sprintf("bundle '%s' with suffix '%s'", require.Name, require.Suffix)
+				if existing, found := origins[installName]; found && existing != origin {
+					v.addf(requirePath+".suffix", "install '%s' would be created from both %s and %s", installName, existing, origin)
+				} else {
+					origins[installName] = origin
+				}
+			}
+
+			requiredApp := apps[require.Name]
+			if requiredApp == nil {
+				continue
+			}
+			for m, parameter := range require.Parameters {
+				parameterPath := fmt.Sprintf("%s.parameters[%d]", requirePath, m)
+				mm.validateParameter(v, parameterPath, requiredApp, parameter)
+			}
+		}
+	}
+
+	// Dependency cycles
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reported := make(map[string]bool)
+	for _, name := range names {
+		cycle := findCycle(name, dependencies, nil)
+		if cycle == nil || reported[cycle[0]] {
+			continue
+		}
+		for _, member := range cycle {
+			reported[member] = true
+		}
+		v.addf(fmt.Sprintf("spec.bundles[%d].requires", bundleIndex[cycle[0]]), "dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return v.problems, nil
+}
+
+// validateBundleParameters checks the parameters of a bundle against its application's definitions
+func (mm *ManifestManager) validateBundleParameters(v *manifestValidation, path string, bundle v1alpha1.BundleSpec, app *v1alpha1.Application, manifestParameters []v1alpha1.ParameterSpec) {
+	for j, parameter := range bundle.Parameters {
+		mm.validateParameter(v, fmt.Sprintf("%s.parameters[%d]", path, j), app, parameter)
+	}
+
+	set := make(map[string]bool)
+	for _, parameter := range bundleParameters(manifestParameters, bundle.Parameters, app.Spec.ParameterDefinitions) {
+		if strings.TrimSpace(parameter.Value) != "" || parameter.ValueFrom != nil || parameter.GenerateSecret.Format != "" {
+			set[parameter.Name] = true
+		}
+	}
+	for _, definition := range app.Spec.ParameterDefinitions {
+		if definition.Required && !set[definition.Name] && definition.GenerateSecret.Format == "" {
+			v.addf(path+".parameters", "required parameter '%s' of application '%s' not set", definition.Name, bundle.Name)
+		}
+	}
+}
+
+func (mm *ManifestManager) validateParameter(v *manifestValidation, path string, app *v1alpha1.Application, parameter v1alpha1.ParameterSpec) {
+	if !hasDefinition(app.Spec.ParameterDefinitions, parameter.Name) {
+		v.addf(path+".name", "parameter '%s' isn't defined by application '%s'", parameter.Name, app.Spec.Name)
+		return
+	}
+	if parameter.ValueFrom != nil || parameter.GenerateSecret.Format != "" {
+		return
+	}
+
+	pm := NewParameterManager(mm.kbClient, app.Namespace, app.Spec.Name, app.Spec.ParameterDefinitions, nil)
+	err := pm.ValidateValue(parameter.Name, parameter.Value)
+	if err != nil {
+		v.addf(path+".value", "%v", err)
+	}
+}
+
+// findCycle returns the dependency cycle starting from name, such as [a b a], or nil if there is none
+func findCycle(name string, dependencies map[string][]string, visiting []string) []string {
+	for i, visited := range visiting {
+		if visited == name {
+			if i != 0 {
+				return nil
+			}
+			return append(append([]string{}, visiting...), name)
+		}
+	}
+
+	visiting = append(visiting, name)
+	for _, dep := range dependencies[name] {
+		cycle := findCycle(dep, dependencies, visiting)
+		if cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func appendUnique(slice []string, item string) []string {
+	if stringSliceContains(slice, item) {
+		return slice
+	}
+	return append(slice, item)
+}
+
+// locateYAMLPath returns the yaml node at a path such as spec.bundles[1].name, or the closest enclosing node if the
+// path doesn't exist
+func locateYAMLPath(root *yamlv3.Node, path string) *yamlv3.Node {
+	node := root
+	if node.Kind == yamlv3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		key := segment
+		index := -1
+		if open := strings.Index(segment, "["); open >= 0 && strings.HasSuffix(segment, "]") {
+			key = segment[:open]
+			i, err := strconv.Atoi(segment[open+1 : len(segment)-1])
+			if err != nil {
+				return node
+			}
+			index = i
+		}
+
+		next := mappingValue(node, key)
+		if next == nil {
+			return node
+		}
+		node = next
+
+		if index >= 0 {
+			if node.Kind != yamlv3.SequenceNode || index >= len(node.Content) {
+				return node
+			}
+			node = node.Content[index]
+		}
+	}
+	return node
+}
+
+// mappingValue returns the value of a key in a yaml mapping, or nil
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
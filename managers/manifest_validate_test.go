@@ -0,0 +1,140 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const invalidManifest = `apiVersion: bundle.splunk.com/v1alpha1
+kind: Manifest
+metadata:
+  name: invalid
+spec:
+  sources:
+    - name: local
+    - name: missing
+  parameters:
+    - name: unused
+      value: x
+  bundles:
+    - name: web
+      version: 1.0.0
+      parameters:
+        - name: replicas
+          value: two
+        - name: colour
+          value: blue
+      requires:
+        - name: db
+          suffix: web
+        - name: cache
+    - name: db
+      version: 1.0.0
+    - name: db-web
+      version: 1.0.0
+    - name: ghost
+      version: 1.0.0
+    - name: a
+      version: 1.0.0
+      requires:
+        - name: b
+    - name: b
+      version: 1.0.0
+      retries: -1
+      requires:
+        - name: a
+`
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+	writeApp(t, dir, "web", "spec:\n  name: web\n  version: 1.0.0\n  parameters:\n    - name: replicas\n      type: int\n    - name: domain\n      required: true\n")
+	writeApp(t, dir, "db", "spec:\n  name: db\n  version: 1.0.0\n")
+	writeApp(t, dir, "db-web", "spec:\n  name: db-web\n  version: 1.0.0\n")
+	writeApp(t, dir, "a", "spec:\n  name: a\n  version: 1.0.0\n")
+	writeApp(t, dir, "b", "spec:\n  name: b\n  version: 1.0.0\n")
+
+	manifestFile := filepath.Join(dir, "manifest.yaml")
+	err := os.WriteFile(manifestFile, []byte(invalidManifest), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	err = v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &v1alpha1.Source{
+		ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"},
+		Spec:       v1alpha1.SourceSpec{Type: "directory", Path: dir},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(source).Build()
+	mm := NewManifestManager(KBClient{Client: c})
+
+	problems, err := mm.ValidateFile(context.Background(), manifestFile, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.String())
+	}
+	sort.Strings(got)
+
+	for _, want := range []string{
+		"8:13: spec.sources[1].name: source 'missing' not found",
+		"17:18: spec.bundles[0].parameters[0].value: invalid value for parameter 'replicas'",
+		"18:17: spec.bundles[0].parameters[1].name: parameter 'colour' isn't defined by application 'web'",
+		"16:9: spec.bundles[0].parameters: required parameter 'domain' of application 'web' not set",
+		"22:19: spec.bundles[0].requires[0].suffix: install 'db-web' would be created from both bundle 'db-web' and bundle 'db' with suffix 'web'",
+		"23:17: spec.bundles[0].requires[1].name: requires 'cache', which isn't a bundle in the manifest",
+		"28:13: spec.bundles[3].name: bundle 'ghost' version '1.0.0' not found in sources",
+		"33:9: spec.bundles[4].requires: dependency cycle: a -> b -> a",
+		"36:16: spec.bundles[5].retries: retries must not be negative",
+	} {
+		found := false
+		for _, problem := range got {
+			if strings.Contains(problem, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected problem %q, got:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+	if len(got) != 9 {
+		t.Errorf("expected 9 problems, got %d:\n%s", len(got), strings.Join(got, "\n"))
+	}
+}
+
+func writeApp(t *testing.T, dir, name, appYAML string) {
+	t.Helper()
+	writeBundleFile(t, filepath.Join(dir, name+"-1.0.0.kb"), appYAML)
+}
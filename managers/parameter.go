@@ -18,89 +18,358 @@ package managers
 
 import (
 	"context"
+	"crypto"
 	crand "crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	v1alpha1 "github.com/splunk/kube-bundler/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
+	// secretName is the global secret that held generated values before they were stored per install
 	secretName       = "global-secret"
 	defaultNamespace = "default"
+
+	caSecretName = "kube-bundler-ca"
+	caCommonName = "kube-bundler CA"
+	caValidity   = 10 * 365 * 24 * time.Hour
 )
 
 type ParameterDesc struct {
-	Value       string
-	Default     string
-	Description string
+	Value       string `json:"value"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Sensitive   bool   `json:"sensitive"`
 }
 
+// maskedValue replaces the values of sensitive parameters in descriptions and listings
+const maskedValue = "********"
+
+// errNotGenerated is returned in read-only mode for generated parameters that don't have a value yet
+var errNotGenerated = errors.New("secret value hasn't been generated yet")
+
 type ParameterManager struct {
-	kbClient    KBClient
-	installName string
-	definitions []v1alpha1.ParameterDefinitionSpec
-	parameters  []v1alpha1.ParameterSpec
+	kbClient     KBClient
+	installName  string
+	definitions  []v1alpha1.ParameterDefinitionSpec
+	parameters   []v1alpha1.ParameterSpec
+	namespace    string
+	templateData *ParameterTemplateData
+	readOnly     bool
 }
 
-func NewParameterManager(kbClient KBClient, installName string, definitions []v1alpha1.ParameterDefinitionSpec, parameters []v1alpha1.ParameterSpec) *ParameterManager {
+// NewParameterManager returns a parameter manager for an install. namespace is the install's namespace, in which
+// generated secrets are stored and Secrets and ConfigMaps referenced by valueFrom are read.
+func NewParameterManager(kbClient KBClient, namespace, installName string, definitions []v1alpha1.ParameterDefinitionSpec, parameters []v1alpha1.ParameterSpec) *ParameterManager {
 	return &ParameterManager{
 		kbClient:    kbClient,
 		installName: installName,
 		definitions: definitions,
 		parameters:  parameters,
+		namespace:   namespace,
 	}
 }
 
+// SetTemplateData enables template evaluation of parameter defaults and values in GetSplitMaps and GetMergedMap.
+// Without template data, templates are returned as written.
+func (pm *ParameterManager) SetTemplateData(templateData ParameterTemplateData) {
+	pm.templateData = &templateData
+}
+
+// SetReadOnly stops GetSplitMaps and GetMergedMap from generating missing secret values and the CA, or moving values
+// out of the global secret. A generated parameter without a stored value fails with errNotGenerated instead.
+func (pm *ParameterManager) SetReadOnly() {
+	pm.readOnly = true
+}
+
 // GetMergedMap returns a parameter map with all overridden parameters merged in
 func (pm *ParameterManager) GetMergedMap() (map[string]string, error) {
+	parameters, secrets, err := pm.GetSplitMaps()
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range secrets {
+		parameters[name] = value
+	}
+	return parameters, nil
+}
+
+// GetDisplayMap returns the merged parameters, showing parameters read from a Secret or ConfigMap by reference instead
+// of by value. Generated and sensitive values are masked.
+func (pm *ParameterManager) GetDisplayMap() (map[string]string, error) {
+	sensitive := make(map[string]bool)
+	display := *pm
+	display.templateData = nil
+	display.definitions = make([]v1alpha1.ParameterDefinitionSpec, len(pm.definitions))
+	for i, definition := range pm.definitions {
+		sensitive[definition.Name] = definition.Sensitive
+		if definition.GenerateSecret.Format != "" {
+			definition.GenerateSecret = v1alpha1.GenerateSecret{}
+			definition.Default = maskedValue
+		} else if definition.Sensitive && definition.Default != "" {
+			definition.Default = maskedValue
+		}
+		display.definitions[i] = definition
+	}
+	display.parameters = make([]v1alpha1.ParameterSpec, len(pm.parameters))
+	for i, parameter := range pm.parameters {
+		switch {
+		case parameter.ValueFrom != nil:
+			parameter = v1alpha1.ParameterSpec{Name: parameter.Name, Value: describeValueFrom(parameter.ValueFrom)}
+		case parameter.GenerateSecret.Format != "":
+			parameter = v1alpha1.ParameterSpec{Name: parameter.Name, Value: maskedValue}
+		case sensitive[parameter.Name] && parameter.Value != "":
+			parameter.Value = maskedValue
+		}
+		display.parameters[i] = parameter
+	}
+	return display.GetMergedMap()
+}
+
+// GetSplitMaps returns the merged parameters separated into non-sensitive values and secret values. Generated and
+// sensitive parameters are secret.
+func (pm *ParameterManager) GetSplitMaps() (map[string]string, map[string]string, error) {
 	m := make(map[string]string, len(pm.definitions))
+	secret := make(map[string]bool)
+	// literal holds parameters that aren't templates, since they aren't defined with template or their values are
+	// generated or read from the cluster
+	literal := make(map[string]bool)
+	templated := make(map[string]bool)
 	for _, parameter := range pm.definitions {
+		templated[parameter.Name] = parameter.Template
 		if parameter.GenerateSecret.Format != "" {
 			parameterSecretValue, err := pm.getSecretValue(parameter.Name, parameter.GenerateSecret)
 			if err != nil {
-				return nil, errors.Wrap(err, "Failed to get secret value")
+				return nil, nil, errors.Wrap(err, "Failed to get secret value")
 			}
 			m[parameter.Name] = parameterSecretValue
+			secret[parameter.Name] = true
+			literal[parameter.Name] = true
 		} else {
 			m[parameter.Name] = parameter.Default
+			secret[parameter.Name] = parameter.Sensitive
+			literal[parameter.Name] = !parameter.Template
 		}
 	}
 
 	// Apply overrides
 	for _, parameter := range pm.parameters {
-		if parameter.GenerateSecret.Format != "" {
-			parameterSecretValue, err := pm.getSecretValue(parameter.Name, parameter.GenerateSecret)
-			if err != nil {
-				return nil, errors.Wrap(err, "Failed to get secret value")
-			}
-			m[parameter.Name] = parameterSecretValue
+		value, err := pm.getValue(parameter)
+		if err != nil {
+			return nil, nil, err
+		}
+		m[parameter.Name] = value
+		literal[parameter.Name] = !templated[parameter.Name] || parameter.GenerateSecret.Format != "" || parameter.ValueFrom != nil
+		if parameter.GenerateSecret.Format != "" || isSecretRef(parameter.ValueFrom) {
+			secret[parameter.Name] = true
+		}
+	}
+
+	if pm.templateData != nil {
+		rendered, err := pm.renderTemplates(m, secret, literal)
+		if err != nil {
+			return nil, nil, err
+		}
+		m = rendered
+	}
+
+	parameters := make(map[string]string, len(m))
+	secrets := make(map[string]string, len(secret))
+	for name, value := range m {
+		if secret[name] {
+			secrets[name] = value
 		} else {
-			m[parameter.Name] = parameter.Value
+			parameters[name] = value
 		}
 	}
 
+	return parameters, secrets, nil
+}
+
+// GetSecretsMap returns the values of the given install secrets
+func (pm *ParameterManager) GetSecretsMap(secrets []v1alpha1.ParameterSpec) (map[string]string, error) {
+	m := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		value, err := pm.getValue(secret)
+		if err != nil {
+			return nil, err
+		}
+		m[secret.Name] = value
+	}
 	return m, nil
 }
 
+// IsSecret returns whether the named parameter holds a secret value
+func (pm *ParameterManager) IsSecret(name string) bool {
+	for _, definition := range pm.definitions {
+		if definition.Name == name && (definition.GenerateSecret.Format != "" || definition.Sensitive) {
+			return true
+		}
+	}
+	for _, parameter := range pm.parameters {
+		if parameter.Name == name && (parameter.GenerateSecret.Format != "" || isSecretRef(parameter.ValueFrom)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getValue returns the literal value of a parameter, or its generated value if it uses generateSecret
+func (pm *ParameterManager) getValue(parameter v1alpha1.ParameterSpec) (string, error) {
+	if parameter.ValueFrom != nil {
+		value, err := pm.getValueFrom(parameter.ValueFrom)
+		if err != nil {
+			return "", errors.Wrapf(err, "couldn't get value for parameter '%s'", parameter.Name)
+		}
+		return value, nil
+	}
+
+	if parameter.GenerateSecret.Format == "" {
+		return parameter.Value, nil
+	}
+
+	parameterSecretValue, err := pm.getSecretValue(parameter.Name, parameter.GenerateSecret)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get secret value")
+	}
+	return parameterSecretValue, nil
+}
+
+// generatedParameters returns the GenerateSecret spec of each generated parameter, with overrides applied
+func (pm *ParameterManager) generatedParameters() map[string]v1alpha1.GenerateSecret {
+	generated := make(map[string]v1alpha1.GenerateSecret)
+	for _, definition := range pm.definitions {
+		if definition.GenerateSecret.Format != "" {
+			generated[definition.Name] = definition.GenerateSecret
+		}
+	}
+	for _, parameter := range pm.parameters {
+		if parameter.GenerateSecret.Format != "" {
+			generated[parameter.Name] = parameter.GenerateSecret
+		} else {
+			delete(generated, parameter.Name)
+		}
+	}
+	return generated
+}
+
+// GetPreviousSecrets returns the previous values of rotated parameters, keyed by <parameter>_previous
+func (pm *ParameterManager) GetPreviousSecrets() (map[string]string, error) {
+	previous := make(map[string]string)
+	generated := pm.generatedParameters()
+	if len(generated) == 0 {
+		return previous, nil
+	}
+
+	store := newGeneratedSecretStore(pm.kbClient, pm.installName, pm.namespace)
+	secret, err := store.get(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get generated secrets")
+	}
+
+	for name := range generated {
+		value, found, err := store.value(context.TODO(), secret, name+PreviousSuffix)
+		if err != nil {
+			return nil, err
+		} else if found {
+			previous[name+PreviousSuffix] = value
+		}
+	}
+	return previous, nil
+}
+
+// getValueFrom reads a value referenced by valueFrom. A missing optional reference resolves to an empty value.
+func (pm *ParameterManager) getValueFrom(valueFrom *v1alpha1.ParameterValueSource) (string, error) {
+	clientset := pm.kbClient.Interface
+
+	switch {
+	case valueFrom.SecretKeyRef != nil:
+		ref := valueFrom.SecretKeyRef
+		optional := ref.Optional != nil && *ref.Optional
+		secret, err := clientset.CoreV1().Secrets(pm.namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) && optional {
+			return "", nil
+		} else if err != nil {
+			return "", errors.Wrapf(err, "couldn't get secret %q", ref.Name)
+		}
+		value, found := secret.Data[ref.Key]
+		if !found && !optional {
+			return "", fmt.Errorf("key '%s' not found in secret %q", ref.Key, ref.Name)
+		}
+		return string(value), nil
+	case valueFrom.ConfigMapKeyRef != nil:
+		ref := valueFrom.ConfigMapKeyRef
+		optional := ref.Optional != nil && *ref.Optional
+		cm, err := clientset.CoreV1().ConfigMaps(pm.namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) && optional {
+			return "", nil
+		} else if err != nil {
+			return "", errors.Wrapf(err, "couldn't get configmap %q", ref.Name)
+		}
+		value, found := cm.Data[ref.Key]
+		if !found && !optional {
+			return "", fmt.Errorf("key '%s' not found in configmap %q", ref.Key, ref.Name)
+		}
+		return value, nil
+	default:
+		return "", errors.New("valueFrom must set secretKeyRef or configMapKeyRef")
+	}
+}
+
+// isSecretRef returns whether valueFrom reads from a Secret
+func isSecretRef(valueFrom *v1alpha1.ParameterValueSource) bool {
+	return valueFrom != nil && valueFrom.SecretKeyRef != nil
+}
+
+// describeValueFrom returns a placeholder shown instead of a value read from a Secret or ConfigMap
+func describeValueFrom(valueFrom *v1alpha1.ParameterValueSource) string {
+	switch {
+	case valueFrom.SecretKeyRef != nil:
+		return fmt.Sprintf("<from secret %s/%s>", valueFrom.SecretKeyRef.Name, valueFrom.SecretKeyRef.Key)
+	case valueFrom.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("<from configmap %s/%s>", valueFrom.ConfigMapKeyRef.Name, valueFrom.ConfigMapKeyRef.Key)
+	default:
+		return "<from unknown source>"
+	}
+}
+
 // GetParameterDesc returns a map of Parameter structs where the map key is the name
 func (pm *ParameterManager) GetParameterDesc() map[string]ParameterDesc {
 	m := make(map[string]ParameterDesc, len(pm.definitions))
 	for _, parameter := range pm.definitions {
-		m[parameter.Name] = ParameterDesc{Value: parameter.Default, Default: parameter.Default, Description: parameter.Description}
+		paramType := parameter.Type
+		if paramType == "" {
+			paramType = v1alpha1.ParameterTypeString
+		}
+		m[parameter.Name] = ParameterDesc{
+			Value:       parameter.Default,
+			Default:     parameter.Default,
+			Description: parameter.Description,
+			Type:        paramType,
+			Required:    parameter.Required,
+			Sensitive:   parameter.Sensitive,
+		}
 	}
 
 	// Apply overrides
 	for _, parameter := range pm.parameters {
 		desc := m[parameter.Name]
 		desc.Value = parameter.Value
+		if parameter.ValueFrom != nil {
+			desc.Value = describeValueFrom(parameter.ValueFrom)
+		}
 		m[parameter.Name] = desc
 	}
 
@@ -123,8 +392,9 @@ func (pm *ParameterManager) MergeAdditionalParameters(additionalParameters []v1a
 		}
 		if !found {
 			paramSpec := v1alpha1.ParameterSpec{
-				Name:  additionalParameter.Name,
-				Value: additionalParameter.Value,
+				Name:      additionalParameter.Name,
+				Value:     additionalParameter.Value,
+				ValueFrom: additionalParameter.ValueFrom,
 			}
 			paramsArray = append(paramsArray, paramSpec)
 		}
@@ -134,93 +404,185 @@ func (pm *ParameterManager) MergeAdditionalParameters(additionalParameters []v1a
 	return sourceParameters
 }
 
+// Validate checks that required parameters are set, and that literal parameter values and defaults match the type and
+// constraints of their definitions. All problems are reported together.
 func (pm *ParameterManager) Validate() error {
 	m := make(map[string]v1alpha1.ParameterSpec, len(pm.parameters))
 	for _, parameter := range pm.parameters {
-		m[parameter.Name] = v1alpha1.ParameterSpec{Name: parameter.Name, Value: parameter.Value}
+		m[parameter.Name] = parameter
 	}
 
+	var problems []string
 	for _, definition := range pm.definitions {
-		parameterSpec := m[definition.Name]
+		parameterSpec, overridden := m[definition.Name]
 		value := parameterSpec.Value
-		if definition.Required && strings.TrimSpace(value) == "" {
-			return fmt.Errorf("required parameter '%s' not set", definition.Name)
+		if definition.Required && strings.TrimSpace(value) == "" && parameterSpec.ValueFrom == nil {
+			problems = append(problems, fmt.Sprintf("required parameter '%s' not set", definition.Name))
+			continue
+		}
+
+		// Generated values and values read from the cluster aren't validated
+		if parameterSpec.GenerateSecret.Format != "" || parameterSpec.ValueFrom != nil || (!overridden && definition.GenerateSecret.Format != "") {
+			continue
+		}
+		if !overridden {
+			value = definition.Default
+		}
+
+		// Templates are validated once evaluated
+		if isTemplate(definition, value) {
+			continue
+		}
+
+		err := validateParameterValue(definition, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parameter '%s': %v", definition.Name, err))
 		}
 	}
 
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateValue checks a single parameter value against its definition
+func (pm *ParameterManager) ValidateValue(name, value string) error {
+	for _, definition := range pm.definitions {
+		if definition.Name == name && !isTemplate(definition, value) {
+			err := validateParameterValue(definition, value)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value for parameter '%s'", name)
+			}
+			return nil
+		}
+	}
 	return nil
 }
 
 func (pm *ParameterManager) getSecretValue(parameterName string, generateSecret v1alpha1.GenerateSecret) (string, error) {
-	secret, err := getGlobalSecret(pm.kbClient)
+	store := newGeneratedSecretStore(pm.kbClient, pm.installName, pm.namespace)
+	get := store.get
+	if pm.readOnly {
+		get = store.peek
+	}
+	secret, err := get(context.TODO())
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to get global secret")
+		return "", errors.Wrap(err, "Failed to get generated secrets")
 	}
 
-	// Check if install-name.parameter-name is in the global secret
-	secretKey := pm.installName + "." + parameterName
-	secretValue, found := secret.Data[secretKey]
-	if found {
-		return string(secretValue[:]), nil
-	} else {
-		// If not found, generate new secret value
-		parameterSecretValue, err := generateSecretValue(generateSecret.Format, generateSecret.Bytes, generateSecret.Bits)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to generate secret value")
-		}
-		// Add the new secret value to the global secret
-		secret.Data[secretKey] = []byte(parameterSecretValue)
-		clientset := pm.kbClient.Interface
-		_, err = clientset.CoreV1().Secrets(defaultNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to update global secret")
-		}
+	secretValue, found, err := store.value(context.TODO(), secret, parameterName)
+	if err != nil {
+		return "", err
+	} else if found {
+		return secretValue, nil
+	} else if pm.readOnly {
+		return "", errors.Wrapf(errNotGenerated, "parameter '%s'", parameterName)
+	}
+
+	// If not found, generate new secret value
+	parameterSecretValue, err := generateSecretValue(generateSecret, pm.getCertAuthority)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to generate secret value")
+	}
+	storedValue, err := store.encode(context.TODO(), parameterSecretValue)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to encrypt secret value")
+	}
 
-		return parameterSecretValue, nil
+	// Add the new secret value, unless a concurrent deploy stored one first
+	err = store.update(context.TODO(), func(secret *corev1.Secret) (bool, error) {
+		if _, found := secret.Data[parameterName]; found {
+			value, _, err := store.value(context.TODO(), secret, parameterName)
+			parameterSecretValue = value
+			return false, err
+		}
+		secret.Data[parameterName] = storedValue
+		return true, setGeneratedTime(secret, parameterName, time.Now())
+	}, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to store generated secret")
 	}
+
+	return parameterSecretValue, nil
 }
 
-func getGlobalSecret(kbClient KBClient) (*corev1.Secret, error) {
-	clientset := kbClient.Interface
-	secretClient := clientset.CoreV1().Secrets(defaultNamespace)
+// getCertAuthority returns the CA that signs generated tls certificates. The CA is created on first use and stored next
+// to the global secret.
+func (pm *ParameterManager) getCertAuthority() (*x509.Certificate, crypto.Signer, error) {
+	secretClient := pm.kbClient.Interface.CoreV1().Secrets(pm.namespace)
 
-	secret, err := secretClient.Get(context.TODO(), secretName, metav1.GetOptions{})
-	if err != nil {
-		// Global secret doesn't exist, create it
+	secret, err := secretClient.Get(context.TODO(), caSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		certPEM, keyPEM, err := generateCA(caCommonName, caValidity)
+		if err != nil {
+			return nil, nil, err
+		}
+		storedKey, err := encryptValue(context.TODO(), pm.kbClient.KeyProvider, keyPEM)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to encrypt CA key")
+		}
 		secret = &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      secretName,
-				Namespace: defaultNamespace,
+				Name:      caSecretName,
+				Namespace: pm.namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       certPEM,
+				corev1.TLSPrivateKeyKey: storedKey,
 			},
-			Data: map[string][]byte{},
 		}
 		_, err = secretClient.Create(context.TODO(), secret, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// Another deploy created the CA first
+			secret, err = secretClient.Get(context.TODO(), caSecretName, metav1.GetOptions{})
+		}
 		if err != nil {
-			return nil, errors.Wrap(err, "Failed to create global secret")
+			return nil, nil, errors.Wrap(err, "Failed to create CA secret")
 		}
+	} else if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to get CA secret")
+	}
+
+	keyPEM, err := decryptValue(context.TODO(), pm.kbClient.KeyProvider, secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to decrypt CA key")
 	}
-	return secret, nil
+	return parseCA(secret.Data[corev1.TLSCertKey], keyPEM)
 }
 
-func generateSecretValue(format string, bytes, bits int) (string, error) {
+func generateSecretValue(generateSecret v1alpha1.GenerateSecret, getCA certAuthorityFunc) (string, error) {
 
 	var secretValue string
+	var err error
 
-	if format == "hex" {
-		randomBytes := make([]byte, bytes)
-		_, err := crand.Read(randomBytes)
-		if err != nil {
-			return "", errors.Wrap(err, "Failed to generate random value")
-		}
-		secretValue = hex.EncodeToString(randomBytes)
-	} else if format == "rsa" {
-		rsaKey, err := generatePEMEncodedKey(bits)
+	switch generateSecret.Format {
+	case "hex":
+		secretValue, err = generateHex(generateSecret.Bytes)
+	case "rsa":
+		rsaKey, err := generatePEMEncodedKey(generateSecret.Bits)
 		if err != nil {
 			return "", errors.Wrap(err, "Failed to generate RSA key")
 		}
 		secretValue = string(rsaKey)
-	} else {
-		return "", errors.New("Unknown format: " + format)
+	case "base64":
+		secretValue, err = generateBase64(generateSecret.Bytes)
+	case "password":
+		secretValue, err = generatePassword(generateSecret.Length, generateSecret.Charsets, generateSecret.Symbols)
+	case "uuid":
+		secretValue, err = generateUUID()
+	case "ecdsa":
+		secretValue, err = generateECDSAKey(generateSecret.Curve)
+	case "ed25519":
+		secretValue, err = generateEd25519Key()
+	case "tls":
+		secretValue, err = generateTLS(generateSecret, getCA)
+	default:
+		return "", errors.New("Unknown format: " + generateSecret.Format)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to generate %s value", generateSecret.Format)
 	}
 
 	return secretValue, nil
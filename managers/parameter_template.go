@@ -0,0 +1,345 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+// ParameterTemplateData holds the values available to parameter templates, besides the other parameters
+type ParameterTemplateData struct {
+	// Suffix is the suffix of the install, available as {{ .suffix }}
+	Suffix string
+
+	// Flavor is the flavor of the install, available as {{ .flavor.statelessReplicas }}
+	Flavor v1alpha1.FlavorSpec
+
+	// Outputs holds the outputs of required installs keyed by install name, available as {{ .outputs.nginx.endpoint }}
+	Outputs map[string]map[string]interface{}
+}
+
+// isTemplate returns whether a parameter value needs template evaluation. Only parameters defined with template are
+// evaluated, so other values containing "{{" are used as written.
+func isTemplate(definition v1alpha1.ParameterDefinitionSpec, value string) bool {
+	return definition.Template && strings.Contains(value, "{{")
+}
+
+// parameterRenderer evaluates parameter templates in dependency order. Parameters referenced by a template are
+// evaluated first, and a parameter that references a secret parameter becomes secret itself. Literal parameters, which
+// include every parameter not defined with template, are never evaluated.
+type parameterRenderer struct {
+	raw      map[string]string
+	secret   map[string]bool
+	literal  map[string]bool
+	rendered map[string]string
+	outputs  map[string]map[string]interface{}
+	data     map[string]interface{}
+	visiting []string
+}
+
+func newParameterRenderer(raw map[string]string, secret, literal map[string]bool, templateData ParameterTemplateData) (*parameterRenderer, error) {
+	// Expose the flavor with its json field names
+	b, err := json.Marshal(templateData.Flavor)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't encode flavor")
+	}
+	flavor := make(map[string]interface{})
+	err = json.Unmarshal(b, &flavor)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't decode flavor")
+	}
+
+	outputs := templateData.Outputs
+	if outputs == nil {
+		outputs = make(map[string]map[string]interface{})
+	}
+
+	rendered := make(map[string]string, len(raw))
+	return &parameterRenderer{
+		raw:      raw,
+		secret:   secret,
+		literal:  literal,
+		rendered: rendered,
+		outputs:  outputs,
+		data: map[string]interface{}{
+			"params":  rendered,
+			"suffix":  templateData.Suffix,
+			"flavor":  flavor,
+			"outputs": outputs,
+		},
+	}, nil
+}
+
+// renderAll evaluates every parameter and returns the rendered values
+func (r *parameterRenderer) renderAll() (map[string]string, error) {
+	for name := range r.raw {
+		err := r.render(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.rendered, nil
+}
+
+func (r *parameterRenderer) render(name string) error {
+	if _, done := r.rendered[name]; done {
+		return nil
+	}
+	for i, visiting := range r.visiting {
+		if visiting == name {
+			cycle := append(append([]string{}, r.visiting[i:]...), name)
+			return fmt.Errorf("parameter template cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	value := r.raw[name]
+	if r.literal[name] || !strings.Contains(value, "{{") {
+		r.rendered[name] = value
+		return nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't parse template for parameter '%s'", name)
+	}
+
+	refs, err := templateParameterRefs(tmpl.Tree.Root)
+	if err != nil {
+		return errors.Wrapf(err, "invalid template for parameter '%s'", name)
+	}
+
+	r.visiting = append(r.visiting, name)
+	for _, ref := range refs {
+		if _, found := r.raw[ref]; !found {
+			return fmt.Errorf("parameter '%s' references undefined parameter '%s'", name, ref)
+		}
+		err = r.render(ref)
+		if err != nil {
+			return err
+		}
+		if r.secret[ref] {
+			r.secret[name] = true
+		}
+	}
+	r.visiting = r.visiting[:len(r.visiting)-1]
+
+	// index returns an empty value for a missing key instead of failing, so outputs are checked up front
+	for _, ref := range templateOutputRefs(tmpl.Tree.Root) {
+		outputs, found := r.outputs[ref.Install]
+		if !found {
+			return fmt.Errorf("parameter '%s' references outputs of install '%s', which hasn't saved any", name, ref.Install)
+		}
+		if _, found := outputs[ref.Key]; ref.Key != "" && !found {
+			return fmt.Errorf("parameter '%s' references output '%s' of install '%s', which isn't set", name, ref.Key, ref.Install)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, r.data)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't evaluate template for parameter '%s'", name)
+	}
+	r.rendered[name] = buf.String()
+	return nil
+}
+
+// templateParameterRefs returns the parameters referenced by a template, either as {{ .params.name }},
+// {{ $.params.name }} or {{ index .params "name" }}. Parameters must be referenced by name, so that they're evaluated
+// first and their secrecy carries over. Other uses of .params, like {{ with .params }} or {{ index .params $key }},
+// are rejected.
+func templateParameterRefs(node parse.Node) ([]string, error) {
+	var refs []string
+	var err error
+	indexed := make(map[parse.Node]bool)
+	walkTemplate(node, func(node parse.Node) {
+		if err != nil {
+			return
+		}
+		if n, isCommand := node.(*parse.CommandNode); isCommand {
+			// index .params "name"
+			if path := indexPath(n); len(path) > 1 && path[0] == "params" {
+				refs = append(refs, path[1])
+				indexed[n.Args[1]] = true
+			}
+			return
+		}
+		path, isPath := nodePath(node)
+		if !isPath || len(path) == 0 || path[0] != "params" {
+			return
+		}
+		if len(path) == 1 && !indexed[node] {
+			err = errors.New("parameters must be referenced by name, e.g. {{ .params.name }}")
+			return
+		}
+		if len(path) > 1 {
+			refs = append(refs, path[1])
+		}
+	})
+	return refs, err
+}
+
+// outputRef is an output of a required install referenced by a template. Key is empty when the template references
+// all of the install's outputs.
+type outputRef struct {
+	Install string
+	Key     string
+}
+
+// templateOutputRefs returns the outputs referenced by a template, either as {{ .outputs.install.key }} or with index,
+// e.g. {{ index .outputs "install" "key" }}
+func templateOutputRefs(node parse.Node) []outputRef {
+	var refs []outputRef
+	add := func(path []string) {
+		if len(path) < 2 || path[0] != "outputs" {
+			return
+		}
+		ref := outputRef{Install: path[1]}
+		if len(path) > 2 {
+			ref.Key = path[2]
+		}
+		refs = append(refs, ref)
+	}
+	walkTemplate(node, func(node parse.Node) {
+		if n, isCommand := node.(*parse.CommandNode); isCommand {
+			add(indexPath(n))
+		} else if path, isPath := nodePath(node); isPath {
+			add(path)
+		}
+	})
+	return refs
+}
+
+// nodePath returns the fields of the template data accessed by a field or variable node, e.g. [params host] for
+// {{ .params.host }} or {{ $.params.host }}. Variables other than $ aren't resolved.
+func nodePath(node parse.Node) ([]string, bool) {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return n.Ident, true
+	case *parse.VariableNode:
+		if len(n.Ident) > 0 && n.Ident[0] == "$" {
+			return n.Ident[1:], true
+		}
+	}
+	return nil, false
+}
+
+// indexPath returns the field and keys of an index command, e.g. [outputs nginx endpoint] for
+// {{ index .outputs.nginx "endpoint" }}. Returns nil for other commands, or if a key isn't a constant string.
+func indexPath(n *parse.CommandNode) []string {
+	if len(n.Args) < 3 {
+		return nil
+	}
+	ident, isIdent := n.Args[0].(*parse.IdentifierNode)
+	fields, isPath := nodePath(n.Args[1])
+	if !isIdent || ident.Ident != "index" || !isPath {
+		return nil
+	}
+	path := append([]string{}, fields...)
+	for _, arg := range n.Args[2:] {
+		key, isString := arg.(*parse.StringNode)
+		if !isString {
+			return nil
+		}
+		path = append(path, key.Text)
+	}
+	return path
+}
+
+// walkTemplate calls visit for every node of a template
+func walkTemplate(node parse.Node, visit func(node parse.Node)) {
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			visit(n)
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.FieldNode, *parse.VariableNode:
+			visit(n)
+		}
+	}
+	walk(node)
+}
+
+// renderTemplates evaluates the templates in the merged parameters and validates the results against their definitions.
+// Parameters that reference secret parameters are marked as secret.
+func (pm *ParameterManager) renderTemplates(m map[string]string, secret, literal map[string]bool) (map[string]string, error) {
+	r, err := newParameterRenderer(m, secret, literal, *pm.templateData)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := r.renderAll()
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't evaluate parameters for install %q", pm.installName)
+	}
+
+	var problems []string
+	for _, definition := range pm.definitions {
+		if literal[definition.Name] || !isTemplate(definition, m[definition.Name]) {
+			continue
+		}
+		err = validateParameterValue(definition, rendered[definition.Name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parameter '%s': %v", definition.Name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,173 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	v1alpha1 "github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+func TestGetDisplayMapMasksSecrets(t *testing.T) {
+	definitions := []v1alpha1.ParameterDefinitionSpec{
+		{Name: "host", Default: "db.local"},
+		{Name: "password", Sensitive: true, Default: "hunter2"},
+		{Name: "token", Sensitive: true},
+		{Name: "key", GenerateSecret: v1alpha1.GenerateSecret{Format: "hex", Bytes: 16}},
+	}
+	parameters := []v1alpha1.ParameterSpec{
+		{Name: "token", Value: "abc123"},
+		{Name: "extra", GenerateSecret: v1alpha1.GenerateSecret{Format: "uuid"}},
+	}
+
+	pm := NewParameterManager(KBClient{}, "default", "db", definitions, parameters)
+	m, err := pm.GetDisplayMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"host":     "db.local",
+		"password": maskedValue,
+		"token":    maskedValue,
+		"key":      maskedValue,
+		"extra":    maskedValue,
+	}
+	for name, value := range expected {
+		if m[name] != value {
+			t.Errorf("expected %s to be %q, got %q", name, value, m[name])
+		}
+	}
+}
+
+func TestMissingOutputFailsTemplate(t *testing.T) {
+	outputs := map[string]map[string]interface{}{
+		"nginx": {"endpoint": "http://nginx:80"},
+	}
+	for value, wantErr := range map[string]bool{
+		`{{ .outputs.nginx.endpoint }}`:           false,
+		`{{ index .outputs "nginx" "endpoint" }}`: false,
+		`{{ index .outputs "nginx" "port" }}`:     true,
+		`{{ index .outputs.cache "endpoint" }}`:   true,
+		`{{ .outputs.cache.endpoint }}`:           true,
+	} {
+		definitions := []v1alpha1.ParameterDefinitionSpec{{Name: "upstream", Default: value, Template: true}}
+		pm := NewParameterManager(KBClient{}, "default", "web", definitions, nil)
+		pm.SetTemplateData(ParameterTemplateData{Outputs: outputs})
+		_, err := pm.GetMergedMap()
+		if wantErr && err == nil {
+			t.Errorf("expected %s to fail", value)
+		} else if !wantErr && err != nil {
+			t.Errorf("expected %s to succeed, got %v", value, err)
+		}
+	}
+}
+
+func TestTemplatesAreOptIn(t *testing.T) {
+	definitions := []v1alpha1.ParameterDefinitionSpec{
+		{Name: "host", Default: "db.local"},
+		{Name: "url", Default: "{{ .params.host }}:5432", Template: true},
+		{Name: "motd", Default: "{{ .params.host }}"},
+	}
+	parameters := []v1alpha1.ParameterSpec{
+		{Name: "extra", Value: "{{ .params.host }}"},
+	}
+
+	pm := NewParameterManager(KBClient{}, "default", "db", definitions, parameters)
+	pm.SetTemplateData(ParameterTemplateData{})
+	m, err := pm.GetMergedMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"url":   "db.local:5432",
+		"motd":  "{{ .params.host }}",
+		"extra": "{{ .params.host }}",
+	}
+	for name, value := range expected {
+		if m[name] != value {
+			t.Errorf("expected %s to be %q, got %q", name, value, m[name])
+		}
+	}
+}
+
+func TestTemplateParameterRefs(t *testing.T) {
+	tests := []struct {
+		value   string
+		refs    []string
+		wantErr bool
+	}{
+		{value: `{{ .params.host }}`, refs: []string{"host"}},
+		{value: `{{ $.params.host }}`, refs: []string{"host"}},
+		{value: `{{ index .params "host" }}`, refs: []string{"host"}},
+		{value: `{{ index $.params "host" }}`, refs: []string{"host"}},
+		{value: `{{ (index .params "host") | printf "%s" }}`, refs: []string{"host"}},
+		{value: `{{ if .params.tls }}{{ .params.host }}{{ end }}`, refs: []string{"tls", "host"}},
+		{value: `{{ with .params }}{{ .host }}{{ end }}`, wantErr: true},
+		{value: `{{ $p := .params }}{{ $p.host }}`, wantErr: true},
+		{value: `{{ (.params).host }}`, wantErr: true},
+		{value: `{{ range $k, $v := $.params }}{{ $v }}{{ end }}`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		tmpl, err := template.New("test").Parse(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs, err := templateParameterRefs(tmpl.Tree.Root)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("expected %s to be rejected, got refs %v", test.value, refs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected %s to succeed, got %v", test.value, err)
+		} else if strings.Join(refs, ",") != strings.Join(test.refs, ",") {
+			t.Errorf("expected %s to reference %v, got %v", test.value, test.refs, refs)
+		}
+	}
+}
+
+func TestValidateParameterValueOmitsValue(t *testing.T) {
+	tests := []struct {
+		definition v1alpha1.ParameterDefinitionSpec
+		value      string
+	}{
+		{v1alpha1.ParameterDefinitionSpec{Pattern: "^[a-z]+$"}, "Secret-1"},
+		{v1alpha1.ParameterDefinitionSpec{Enum: []string{"a", "b"}}, "Secret-1"},
+		{v1alpha1.ParameterDefinitionSpec{Type: v1alpha1.ParameterTypeBool}, "Secret-1"},
+		{v1alpha1.ParameterDefinitionSpec{Type: v1alpha1.ParameterTypeJSON}, "Secret-1"},
+		{v1alpha1.ParameterDefinitionSpec{Type: v1alpha1.ParameterTypeInt}, "Secret-1"},
+		{v1alpha1.ParameterDefinitionSpec{Type: v1alpha1.ParameterTypeInt, Max: "10"}, "4242"},
+		{v1alpha1.ParameterDefinitionSpec{Type: v1alpha1.ParameterTypeInt, Min: "5000"}, "4242"},
+	}
+
+	for _, test := range tests {
+		err := validateParameterValue(test.definition, test.value)
+		if err == nil {
+			t.Errorf("expected %+v to reject %q", test.definition, test.value)
+			continue
+		}
+		if strings.Contains(err.Error(), test.value) {
+			t.Errorf("expected error to omit the value, got %q", err.Error())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// validateParameterValue checks a value against the type and constraints of its definition. Empty values are accepted,
+// since whether a value must be set is decided by Required. Errors never include the value, which may be sensitive.
+func validateParameterValue(definition v1alpha1.ParameterDefinitionSpec, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if definition.Pattern != "" {
+		re, err := regexp.Compile(definition.Pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", definition.Pattern)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("value doesn't match pattern %q", definition.Pattern)
+		}
+	}
+
+	if len(definition.Enum) > 0 && !stringSliceContains(definition.Enum, value) {
+		return fmt.Errorf("value must be one of [%s]", strings.Join(definition.Enum, ", "))
+	}
+
+	switch definition.Type {
+	case "", v1alpha1.ParameterTypeString, v1alpha1.ParameterTypeEnum:
+		return nil
+	case v1alpha1.ParameterTypeBool:
+		_, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("value is not a bool")
+		}
+		return nil
+	case v1alpha1.ParameterTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return errors.New("value is not valid json")
+		}
+		return nil
+	case v1alpha1.ParameterTypeInt:
+		return validateRange(definition, value, func(s string) (float64, error) {
+			i, err := strconv.ParseInt(s, 10, 64)
+			return float64(i), err
+		})
+	case v1alpha1.ParameterTypeDuration:
+		return validateRange(definition, value, func(s string) (float64, error) {
+			d, err := time.ParseDuration(s)
+			return float64(d), err
+		})
+	case v1alpha1.ParameterTypeQuantity:
+		return validateRange(definition, value, func(s string) (float64, error) {
+			q, err := resource.ParseQuantity(s)
+			return q.AsApproximateFloat64(), err
+		})
+	default:
+		return fmt.Errorf("unknown parameter type %q", definition.Type)
+	}
+}
+
+// validateRange parses value, min and max with parse and checks that value lies within min and max
+func validateRange(definition v1alpha1.ParameterDefinitionSpec, value string, parse func(string) (float64, error)) error {
+	v, err := parse(value)
+	if err != nil {
+		return fmt.Errorf("value is not a valid %s", definition.Type)
+	}
+
+	if definition.Min != "" {
+		min, err := parse(definition.Min)
+		if err != nil {
+			return fmt.Errorf("min %q is not a valid %s", definition.Min, definition.Type)
+		}
+		if v < min {
+			return fmt.Errorf("value is less than the minimum %s", definition.Min)
+		}
+	}
+
+	if definition.Max != "" {
+		max, err := parse(definition.Max)
+		if err != nil {
+			return fmt.Errorf("max %q is not a valid %s", definition.Max, definition.Type)
+		}
+		if v > max {
+			return fmt.Errorf("value is greater than the maximum %s", definition.Max)
+		}
+	}
+
+	return nil
+}
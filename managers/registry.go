@@ -87,6 +87,7 @@ func NewRegistryManager(c KBClient) *RegistryManager {
 
 type registryArgs struct {
 	RegistryName string
+	Namespace    string
 	Image        string
 	Replicas     int
 	NodeSelector map[string]string
@@ -107,7 +108,7 @@ func (rm *RegistryManager) Deploy(ctx context.Context, registryRef RegistryRef)
 	}
 
 	var flavor v1alpha1.Flavor
-	err = rm.resourceMgr.Get(ctx, flavorName, defaultNamespace, &flavor)
+	err = rm.resourceMgr.Get(ctx, flavorName, registryRef.Namespace, &flavor)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't get flavor %q", flavorName)
 	}
@@ -119,6 +120,7 @@ func (rm *RegistryManager) Deploy(ctx context.Context, registryRef RegistryRef)
 
 	r := registryArgs{
 		RegistryName: registry.SanitizedRegistryName(),
+		Namespace:    registryRef.Namespace,
 		Image:        registry.Spec.Image,
 		Replicas:     flavor.Spec.StatefulQuorumReplicas,
 		NodeSelector: registry.Spec.NodeSelector,
@@ -163,7 +165,7 @@ func (rm *RegistryManager) Deploy(ctx context.Context, registryRef RegistryRef)
 
 	// Wait on registry deployment
 	deployName := fmt.Sprintf("registry-%s", r.RegistryName)
-	err = rm.WaitForRunning(ctx, deployName)
+	err = rm.WaitForRunning(ctx, registryRef.Namespace, deployName)
 	if err != nil {
 		return errors.Wrapf(err, "error waiting on registry Deployment resource %q", deployName)
 	}
@@ -219,16 +221,14 @@ func (rm *RegistryManager) Delete(ctx context.Context, registryRef RegistryRef)
 	return nil
 }
 
-// DeployProxy creates the nginx proxy daemonset for the registries
+// DeployProxy creates the nginx proxy daemonset for the registries. The proxy is shared by the whole cluster, and
+// finds the namespace of a registry in the image path (see Registry.ClusterUrl).
 func (rm *RegistryManager) DeployProxy(ctx context.Context, provider string, gcrInfo GCRInfo) error {
 
 	// port number on the node where the proxy will listen on the cluster (across all nodes)
 	port := "6000"
-	// Namespace where the registry is deployed. This is needed by kube dns resolver when resolving registry svc name
-	registryNamespace := "default"
 	registryProxyConfig := registryProxyConfigYaml
 	registryProxyConfig = strings.ReplaceAll(registryProxyConfig, "__PORT__", port)
-	registryProxyConfig = strings.ReplaceAll(registryProxyConfig, "__REGISTRY_NAMESPACE__", registryNamespace)
 
 	err := rm.applyYaml(ctx, registryProxyConfig)
 	if err != nil {
@@ -382,30 +382,31 @@ func (rm *RegistryManager) ImportToPod(ctx context.Context, pod corev1.Pod, regi
 }
 
 func (rm *RegistryManager) ImportManifest(ctx context.Context, manifestRef ManifestReference, destDir, hostArg string) error {
-	var manifest v1alpha1.Manifest
-	err := rm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &manifest)
+	var base v1alpha1.Manifest
+	err := rm.resourceMgr.Get(ctx, manifestRef.Name, manifestRef.Namespace, &base)
 	if err != nil {
 		return errors.Wrapf(err, "couldn't get manifest %q", manifestRef.Name)
 	}
+	manifest := *MergeManifests(&base, manifestRef.Overlays...)
 
-	var sources []Source
-	for _, sourceInfo := range manifest.Spec.Sources {
-		var src v1alpha1.Source
-		err := rm.resourceMgr.Get(ctx, sourceInfo.Name, manifestRef.Namespace, &src)
-		if err != nil {
-			return errors.Wrapf(err, "couldn't get source '%s'", sourceInfo.Name)
-		}
+	multiSource, err := getManifestSource(ctx, rm.resourceMgr, &manifest, manifestRef.Namespace)
+	if err != nil {
+		return err
+	}
+	if manifestRef.Lock != nil {
+		multiSource = NewLockedSource(multiSource, manifestRef.Lock)
+	}
 
-		newSource, err := NewSource(src.Spec.Type, src.Spec.Path, src.Spec.Options, sourceInfo.Section, sourceInfo.Release)
-		if err != nil {
-			return errors.Wrap(err, "couldn't create new source")
-		}
-		sources = append(sources, newSource)
+	selected, err := manifestRef.Selection.selectBundles(&manifest, multiSource, manifestRef.Namespace)
+	if err != nil {
+		return err
 	}
-	multiSource := NewMultiSource(sources)
 
 	var bundleRefs []BundleRef
 	for _, bundle := range manifest.Spec.Bundles {
+		if !selected[bundle.Name] {
+			continue
+		}
 		bundleRefs = append(bundleRefs, BundleRef{Name: bundle.Name, Version: bundle.Version})
 	}
 
@@ -550,14 +551,15 @@ func (rm *RegistryManager) extractTarToDir(dstDir string, tarContents io.Reader)
 	}
 }
 
-func (rm *RegistryManager) WaitForRunning(ctx context.Context, deployName string) error {
+// WaitForRunning waits for the pods of a deployment in a namespace to be running
+func (rm *RegistryManager) WaitForRunning(ctx context.Context, namespace, deployName string) error {
 	var pods corev1.PodList
 	opts := client.MatchingLabels{"name": deployName}
 
 	err := retry.Do(
 		func() error {
 			numPodsRunning := 0
-			err := rm.resourceMgr.List(ctx, defaultNamespace, &pods, opts)
+			err := rm.resourceMgr.List(ctx, namespace, &pods, opts)
 			if err != nil {
 				return errors.Wrap(err, "couldn't list pods of deployment")
 			}
@@ -112,6 +112,17 @@ func (m *ResourceManager) Patch(ctx context.Context, newObj client.Object, origi
 	return nil
 }
 
+// PatchStatus patches the status subresource of newObj with the changes made from original
+func (m *ResourceManager) PatchStatus(ctx context.Context, newObj client.Object, original client.Object) error {
+	patch := client.MergeFrom(original)
+	err := m.c.Status().Patch(ctx, newObj, patch)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't patch status of resource %q", newObj.GetName())
+	}
+	log.WithFields(log.Fields{"name": newObj.GetName(), "namespace": newObj.GetNamespace(), "kind": newObj.GetObjectKind()}).Debug("Patch resource status")
+	return nil
+}
+
 // Apply applies resources using server-side apply. Similar to `kubectl apply -f`
 func (m *ResourceManager) Apply(ctx context.Context, obj client.Object) error {
 	opts := []client.PatchOption{client.ForceOwnership, client.FieldOwner("kb")}
@@ -65,7 +65,7 @@ func (rsm *RolloutStatusManager) Wait(ctx context.Context, installRef InstallRef
 		return errors.Wrapf(err, "couldn't get Application %q", appName)
 	}
 
-	parameterMgr := NewParameterManager(rsm.kbClient, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+	parameterMgr := NewParameterManager(rsm.kbClient, installRef.Namespace, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
 	m, err := parameterMgr.GetMergedMap()
 	if err != nil {
 		return errors.Wrap(err, "couldn't get merged map")
@@ -88,7 +88,7 @@ func (rsm *RolloutStatusManager) Wait(ctx context.Context, installRef InstallRef
 		case Daemonset:
 			rsrc = resources.NewDaemonSet(rsm.kbClient, resource.Category, installRef.Name, resource.Name+resourceSuffix, resourceNamespace)
 		case Kubegres:
-			rsrc = resources.NewKubegres(rsm.kbClient, resource.Category, installRef.Name, resource.Name+resourceSuffix, resourceNamespace)
+			rsrc = resources.NewKubegres(rsm.kbClient, rsm.kbClient.RestConfig, resource.Category, installRef.Name, resource.Name+resourceSuffix, resourceNamespace)
 		case CronJob:
 			rsrc = resources.NewCronJob(rsm.kbClient, resource.Category, installRef.Name, resource.Name+resourceSuffix, resourceNamespace)
 		case Service:
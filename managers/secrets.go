@@ -0,0 +1,381 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// generatedAtAnnotation holds a json map of secret keys to the time their values were generated
+	generatedAtAnnotation = "bundle.splunk.com/generated-at"
+
+	// PreviousSuffix is appended to the name of a rotated parameter to deliver its previous value
+	PreviousSuffix = "_previous"
+)
+
+// GeneratedSecret describes a generated parameter value
+type GeneratedSecret struct {
+	Install   string `json:"install"`
+	Parameter string `json:"parameter"`
+
+	// GeneratedAt is the time the value was generated or last rotated. It is zero for values generated before
+	// generation times were recorded.
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// HasPrevious is set when the previous value is still delivered to the next deploy
+	HasPrevious bool `json:"hasPrevious"`
+}
+
+type SecretsManager struct {
+	kbClient    KBClient
+	resourceMgr *ResourceManager
+}
+
+func NewSecretsManager(kbClient KBClient) *SecretsManager {
+	return &SecretsManager{
+		kbClient:    kbClient,
+		resourceMgr: NewResourceManager(kbClient),
+	}
+}
+
+// List returns the generated secrets of the given installs in a namespace, or of all installs if none are given.
+// Values still held by the global secret are included.
+func (sm *SecretsManager) List(ctx context.Context, namespace string, installs []string) ([]GeneratedSecret, error) {
+	var generated []GeneratedSecret
+	add := func(installName string, parameterName string, generatedAt time.Time, hasPrevious bool) {
+		if len(installs) > 0 && !stringSliceContains(installs, installName) {
+			return
+		}
+		generated = append(generated, GeneratedSecret{
+			Install:     installName,
+			Parameter:   parameterName,
+			GeneratedAt: generatedAt,
+			HasPrevious: hasPrevious,
+		})
+	}
+
+	var secrets corev1.SecretList
+	err := sm.resourceMgr.List(ctx, namespace, &secrets, client.MatchingLabels{generatedSecretLabel: "true"})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't list generated secrets")
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		generatedAt := getGeneratedTimes(secret)
+		for key := range secret.Data {
+			if strings.HasSuffix(key, PreviousSuffix) {
+				continue
+			}
+			_, hasPrevious := secret.Data[key+PreviousSuffix]
+			add(secret.Labels[installLabel], key, generatedAt[key], hasPrevious)
+		}
+	}
+
+	globalSecret, err := getLegacyGlobalSecret(ctx, sm.kbClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if globalSecret != nil {
+		generatedAt := getGeneratedTimes(globalSecret)
+		for key := range globalSecret.Data {
+			i := strings.LastIndex(key, ".")
+			if i < 0 || strings.HasSuffix(key, PreviousSuffix) {
+				continue
+			}
+			_, hasPrevious := globalSecret.Data[key+PreviousSuffix]
+			add(key[:i], key[i+1:], generatedAt[key], hasPrevious)
+		}
+	}
+
+	sort.Slice(generated, func(i, j int) bool {
+		if generated[i].Install != generated[j].Install {
+			return generated[i].Install < generated[j].Install
+		}
+		return generated[i].Parameter < generated[j].Parameter
+	})
+	return generated, nil
+}
+
+// Rotate regenerates the named generated parameters of an install, or all of them if none are named. The previous
+// values are delivered to the next deploy as <parameter>_previous, so services can accept both during the handover. A
+// previous value that wasn't cleared by a successful deploy is kept. Returns the rotated parameters.
+func (sm *SecretsManager) Rotate(ctx context.Context, installRef InstallReference, parameters []string) ([]string, error) {
+	var install v1alpha1.Install
+	err := sm.resourceMgr.Get(ctx, installRef.Name, installRef.Namespace, &install)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get install %q", installRef.Name)
+	}
+
+	appName := fmt.Sprintf("%s-%s", install.Spec.Application, install.Spec.Version)
+	var app v1alpha1.Application
+	err = sm.resourceMgr.Get(ctx, appName, installRef.Namespace, &app)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get application %q", appName)
+	}
+
+	pm := NewParameterManager(sm.kbClient, installRef.Namespace, installRef.Name, app.Spec.ParameterDefinitions, install.Spec.Parameters)
+	generated := pm.generatedParameters()
+
+	if len(parameters) == 0 {
+		for name := range generated {
+			parameters = append(parameters, name)
+		}
+		sort.Strings(parameters)
+	}
+	for _, name := range parameters {
+		if _, found := generated[name]; !found {
+			return nil, fmt.Errorf("parameter '%s' of install %q isn't a generated secret", name, installRef.Name)
+		}
+	}
+
+	store := newGeneratedSecretStore(sm.kbClient, installRef.Name, installRef.Namespace)
+
+	// Generate the values up front, so conflict retries don't generate them again
+	values := make(map[string][]byte, len(parameters))
+	for _, name := range parameters {
+		value, err := generateSecretValue(generated[name], pm.getCertAuthority)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to generate value for parameter '%s'", name)
+		}
+		values[name], err = store.encode(ctx, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to encrypt value for parameter '%s'", name)
+		}
+	}
+
+	now := time.Now()
+	err = store.update(ctx, func(secret *corev1.Secret) (bool, error) {
+		for _, name := range parameters {
+			// A previous value that wasn't deployed yet is still the one services were last given, so it's kept
+			_, hasPrevious := secret.Data[name+PreviousSuffix]
+			if previous, found := secret.Data[name]; found && !hasPrevious {
+				secret.Data[name+PreviousSuffix] = previous
+			}
+			secret.Data[name] = values[name]
+			err := setGeneratedTime(secret, name, now)
+			if err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range parameters {
+		log.WithFields(log.Fields{"install": installRef.Name, "name": name}).Info("Rotated generated secret")
+	}
+
+	return parameters, nil
+}
+
+// clearPreviousSecrets removes the previous values of rotated parameters of an install once they've been deployed
+func clearPreviousSecrets(ctx context.Context, kbClient KBClient, installRef InstallReference) error {
+	store := newGeneratedSecretStore(kbClient, installRef.Name, installRef.Namespace)
+	return store.update(ctx, func(secret *corev1.Secret) (bool, error) {
+		changed := false
+		for key := range secret.Data {
+			if strings.HasSuffix(key, PreviousSuffix) {
+				delete(secret.Data, key)
+				changed = true
+			}
+		}
+		if changed {
+			log.WithField("install", installRef.Name).Debug("Cleared previous values of rotated secrets")
+		}
+		return changed, nil
+	}, nil)
+}
+
+// getGeneratedTimes returns the recorded generation time of each key of a secret
+func getGeneratedTimes(secret *corev1.Secret) map[string]time.Time {
+	times := make(map[string]time.Time)
+	value, found := secret.Annotations[generatedAtAnnotation]
+	if !found {
+		return times
+	}
+
+	err := json.Unmarshal([]byte(value), &times)
+	if err != nil {
+		log.WithFields(log.Fields{"secret": secret.Name, "err": err}).Warn("Ignoring invalid generated-at annotation")
+		return make(map[string]time.Time)
+	}
+	return times
+}
+
+// setGeneratedTime records the generation time of a key of a secret
+func setGeneratedTime(secret *corev1.Secret, key string, t time.Time) error {
+	times := getGeneratedTimes(secret)
+	times[key] = t.UTC().Truncate(time.Second)
+	return setGeneratedTimes(secret, times)
+}
+
+func setGeneratedTimes(secret *corev1.Secret, times map[string]time.Time) error {
+	if len(times) == 0 {
+		delete(secret.Annotations, generatedAtAnnotation)
+		return nil
+	}
+
+	b, err := json.Marshal(times)
+	if err != nil {
+		return errors.Wrap(err, "couldn't encode generated-at annotation")
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[generatedAtAnnotation] = string(b)
+	return nil
+}
+
+// Reencrypt encrypts the generated secrets of all installs in a namespace and the private key of the namespace's CA with
+// the current key, including values stored before encryption was enabled. With rotateKey, a new current key is created
+// first. Returns the number of values reencrypted.
+func (sm *SecretsManager) Reencrypt(ctx context.Context, namespace string, rotateKey bool) (int, error) {
+	keyProvider := sm.kbClient.KeyProvider
+	if keyProvider == nil {
+		return 0, errors.New("no encryption key configured")
+	}
+
+	if rotateKey {
+		rotator, ok := keyProvider.(KeyRotator)
+		if !ok {
+			return 0, errors.New("the encryption key provider can't create keys; add a new key to the provider instead")
+		}
+		_, err := rotator.RotateKey(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	currentKeyID, err := keyProvider.CurrentKeyID(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't get current encryption key")
+	}
+
+	// Include installs whose values are still in the global secret, so they're migrated and encrypted
+	installNames := make(map[string]bool)
+	var installs v1alpha1.InstallList
+	err = sm.resourceMgr.List(ctx, namespace, &installs)
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't list installs")
+	}
+	for _, install := range installs.Items {
+		installNames[install.Name] = true
+	}
+	var secrets corev1.SecretList
+	err = sm.resourceMgr.List(ctx, namespace, &secrets, client.MatchingLabels{generatedSecretLabel: "true"})
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't list generated secrets")
+	}
+	for _, secret := range secrets.Items {
+		installNames[secret.Labels[installLabel]] = true
+	}
+
+	count := 0
+	for installName := range installNames {
+		store := newGeneratedSecretStore(sm.kbClient, installName, namespace)
+		reencrypted := 0
+		err = store.update(ctx, func(secret *corev1.Secret) (bool, error) {
+			// Conflicts retry with a fresh copy of the secret
+			reencrypted = 0
+			for key, stored := range secret.Data {
+				if !needsReencryption(stored, currentKeyID) {
+					continue
+				}
+				value, _, err := store.value(ctx, secret, key)
+				if err != nil {
+					return false, err
+				}
+				secret.Data[key], err = store.encode(ctx, value)
+				if err != nil {
+					return false, errors.Wrapf(err, "couldn't encrypt '%s'", key)
+				}
+				reencrypted++
+			}
+			return reencrypted > 0, nil
+		}, nil)
+		if err != nil {
+			return count, errors.Wrapf(err, "couldn't reencrypt secrets of install %q", installName)
+		}
+		count += reencrypted
+	}
+
+	reencrypted, err := sm.reencryptCertAuthority(ctx, namespace, currentKeyID)
+	if err != nil {
+		return count, err
+	}
+	if reencrypted {
+		count++
+	}
+
+	return count, nil
+}
+
+// reencryptCertAuthority encrypts the private key of the CA that signs generated tls certificates with the current key.
+// Returns whether the key was reencrypted.
+func (sm *SecretsManager) reencryptCertAuthority(ctx context.Context, namespace, currentKeyID string) (bool, error) {
+	secretClient := sm.kbClient.Interface.CoreV1().Secrets(namespace)
+	reencrypted := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		reencrypted = false
+		secret, err := secretClient.Get(ctx, caSecretName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		stored := secret.Data[corev1.TLSPrivateKeyKey]
+		if !needsReencryption(stored, currentKeyID) {
+			return nil
+		}
+		keyPEM, err := decryptValue(ctx, sm.kbClient.KeyProvider, stored)
+		if err != nil {
+			return err
+		}
+		secret.Data[corev1.TLSPrivateKeyKey], err = encryptValue(ctx, sm.kbClient.KeyProvider, keyPEM)
+		if err != nil {
+			return err
+		}
+		_, err = secretClient.Update(ctx, secret, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		reencrypted = true
+		return nil
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "couldn't reencrypt secret %q", caSecretName)
+	}
+	return reencrypted, nil
+}
@@ -0,0 +1,157 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRotateKeepsUndeployedPrevious(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	install := &v1alpha1.Install{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       v1alpha1.InstallSpec{Application: "db", Version: "1.0.0"},
+	}
+	app := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.ApplicationSpec{
+			Name:    "db",
+			Version: "1.0.0",
+			ParameterDefinitions: []v1alpha1.ParameterDefinitionSpec{
+				{Name: "password", GenerateSecret: v1alpha1.GenerateSecret{Format: "hex", Bytes: 16}},
+			},
+		},
+	}
+	generated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-generated", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("deployed")},
+	}
+	kbClient := KBClient{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(install, app).Build(),
+		Interface: kubefake.NewSimpleClientset(generated),
+	}
+	sm := NewSecretsManager(kbClient)
+	installRef := InstallReference{Name: "db", Namespace: "default"}
+
+	// Rotating twice without a successful deploy in between must keep the deployed value as the previous one
+	for i := 0; i < 2; i++ {
+		_, err = sm.Rotate(context.Background(), installRef, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secret, err := kbClient.CoreV1().Secrets("default").Get(context.Background(), "db-generated", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous := string(secret.Data["password"+PreviousSuffix]); previous != "deployed" {
+		t.Errorf("expected previous value %q, got %q", "deployed", previous)
+	}
+	if current := string(secret.Data["password"]); current == "deployed" || len(current) != 32 {
+		t.Errorf("expected a new value, got %q", current)
+	}
+}
+
+func TestMigrateMatchesInstallName(t *testing.T) {
+	global := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "default"},
+		Data: map[string][]byte{
+			"web.password":     []byte("web"),
+			"web.api.password": []byte("web.api"),
+		},
+	}
+	kbClient := KBClient{Interface: kubefake.NewSimpleClientset(global)}
+
+	secret, err := newGeneratedSecretStore(kbClient, "web", "default").get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.Data) != 1 || string(secret.Data["password"]) != "web" {
+		t.Errorf("expected only the password of web, got %v", secret.Data)
+	}
+
+	global, err = kbClient.CoreV1().Secrets("default").Get(context.Background(), secretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := global.Data["web.api.password"]; !found {
+		t.Error("expected the password of web.api to stay in the global secret")
+	}
+}
+
+func TestReencryptCertAuthority(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = clientgoscheme.AddToScheme(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyProvider, err := NewLocalKeyProvider(writeKeyFile(t, "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A CA created before encryption was enabled
+	plain := KBClient{Interface: kubefake.NewSimpleClientset()}
+	_, _, err = NewParameterManager(plain, "default", "web", nil, nil).getCertAuthority()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kbClient := KBClient{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Interface:   plain.Interface,
+		KeyProvider: keyProvider,
+	}
+	count, err := NewSecretsManager(kbClient).Reencrypt(context.Background(), "default", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 value reencrypted, got %d", count)
+	}
+
+	secret, err := kbClient.CoreV1().Secrets("default").Get(context.Background(), caSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(secret.Data[corev1.TLSPrivateKeyKey]) {
+		t.Error("expected the CA key to be encrypted")
+	}
+	_, _, err = NewParameterManager(kbClient, "default", "web", nil, nil).getCertAuthority()
+	if err != nil {
+		t.Errorf("expected the encrypted CA to be readable, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// generatedSecretLabel marks the Secrets holding generated parameter values
+	generatedSecretLabel = "bundle.splunk.com/generated-secrets"
+
+	// installLabel holds the name of the install a generated Secret belongs to
+	installLabel = "bundle.splunk.com/install"
+)
+
+// generatedSecretStore keeps the generated parameter values of one install in a Secret named <install>-generated, in
+// the install's namespace. Values are keyed by parameter name, and encrypted when the client has a key provider. Values
+// generated by earlier versions of kube-bundler are moved from the global secret the first time the store is read.
+type generatedSecretStore struct {
+	kbClient    KBClient
+	installName string
+	namespace   string
+}
+
+func newGeneratedSecretStore(kbClient KBClient, installName, namespace string) *generatedSecretStore {
+	return &generatedSecretStore{
+		kbClient:    kbClient,
+		installName: installName,
+		namespace:   namespace,
+	}
+}
+
+func (s *generatedSecretStore) name() string {
+	return s.installName + "-generated"
+}
+
+// value returns the decrypted value stored under key
+func (s *generatedSecretStore) value(ctx context.Context, secret *corev1.Secret, key string) (string, bool, error) {
+	stored, found := secret.Data[key]
+	if !found {
+		return "", false, nil
+	}
+	value, err := decryptValue(ctx, s.kbClient.KeyProvider, stored)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "couldn't decrypt '%s' in secret %q", key, s.name())
+	}
+	return string(value), true, nil
+}
+
+// encode returns a value as stored, encrypted if a key provider is configured
+func (s *generatedSecretStore) encode(ctx context.Context, value string) ([]byte, error) {
+	return encryptValue(ctx, s.kbClient.KeyProvider, []byte(value))
+}
+
+// get returns the install's Secret. A Secret that doesn't exist yet is returned empty, without being created.
+func (s *generatedSecretStore) get(ctx context.Context) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	err := s.update(ctx, func(*corev1.Secret) (bool, error) {
+		return false, nil
+	}, &secret)
+	return secret, err
+}
+
+// peek returns the install's Secret with the values of the global secret merged in, without writing either of them
+func (s *generatedSecretStore) peek(ctx context.Context) (*corev1.Secret, error) {
+	secret, err := s.kbClient.Interface.CoreV1().Secrets(s.namespace).Get(ctx, s.name(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get secret %q", s.name())
+	}
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+
+	_, err = s.migrate(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// update reads the install's Secret, applies mutate, and writes it back if mutate reports a change. The read-modify-
+// write is retried when another writer updated the Secret in between. The final Secret is stored in result, if set.
+func (s *generatedSecretStore) update(ctx context.Context, mutate func(secret *corev1.Secret) (bool, error), result **corev1.Secret) error {
+	secretClient := s.kbClient.Interface.CoreV1().Secrets(s.namespace)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secretClient.Get(ctx, s.name(), metav1.GetOptions{})
+		create := apierrors.IsNotFound(err)
+		if create {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.name(),
+					Namespace: s.namespace,
+					Labels: map[string]string{
+						generatedSecretLabel: "true",
+						installLabel:         s.installName,
+					},
+				},
+			}
+		} else if err != nil {
+			return errors.Wrapf(err, "Failed to get secret %q", s.name())
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+
+		legacyKeys, err := s.migrate(ctx, secret)
+		if err != nil {
+			return err
+		}
+
+		changed, err := mutate(secret)
+		if err != nil {
+			return err
+		}
+
+		if create && (changed || len(legacyKeys) > 0) {
+			secret, err = secretClient.Create(ctx, secret, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Another writer created the secret first, so start over with its copy
+				return apierrors.NewConflict(corev1.Resource("secrets"), s.name(), err)
+			}
+		} else if !create && (changed || len(legacyKeys) > 0) {
+			secret, err = secretClient.Update(ctx, secret, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(legacyKeys) > 0 {
+			err = removeLegacySecrets(ctx, s.kbClient, s.namespace, legacyKeys)
+			if err != nil {
+				return err
+			}
+			log.WithFields(log.Fields{"install": s.installName, "secret": s.name()}).Info("Moved generated secrets out of the global secret")
+		}
+
+		if result != nil {
+			*result = secret
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to update secret %q", s.name())
+	}
+	return nil
+}
+
+// migrate copies the install's values from the global secret into secret, and returns the global secret keys copied.
+// Values already in secret are kept.
+func (s *generatedSecretStore) migrate(ctx context.Context, secret *corev1.Secret) ([]string, error) {
+	globalSecret, err := getLegacyGlobalSecret(ctx, s.kbClient, s.namespace)
+	if err != nil || globalSecret == nil {
+		return nil, err
+	}
+
+	// Keys are <install>.<parameter>, split at the last dot as SecretsManager.List does, so that the values of an install
+	// named <install>.<other> aren't taken
+	globalTimes := getGeneratedTimes(globalSecret)
+	var legacyKeys []string
+	for key, value := range globalSecret.Data {
+		i := strings.LastIndex(key, ".")
+		if i < 0 || key[:i] != s.installName || i == len(key)-1 {
+			continue
+		}
+		name := key[i+1:]
+		if _, found := secret.Data[name]; !found {
+			secret.Data[name], err = encryptValue(ctx, s.kbClient.KeyProvider, value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "couldn't encrypt '%s'", key)
+			}
+			if t, found := globalTimes[key]; found {
+				err = setGeneratedTime(secret, name, t)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		legacyKeys = append(legacyKeys, key)
+	}
+	return legacyKeys, nil
+}
+
+// getLegacyGlobalSecret returns the global secret that held the generated values of all installs in a namespace, or
+// nil if it doesn't exist
+func getLegacyGlobalSecret(ctx context.Context, kbClient KBClient, namespace string) (*corev1.Secret, error) {
+	secret, err := kbClient.Interface.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Failed to get global secret")
+	}
+	return secret, nil
+}
+
+// removeLegacySecrets deletes migrated keys from the global secret
+func removeLegacySecrets(ctx context.Context, kbClient KBClient, namespace string, keys []string) error {
+	secretClient := kbClient.Interface.CoreV1().Secrets(namespace)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secretClient.Get(ctx, secretName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		times := getGeneratedTimes(secret)
+		for _, key := range keys {
+			delete(secret.Data, key)
+			delete(times, key)
+		}
+		err = setGeneratedTimes(secret, times)
+		if err != nil {
+			return err
+		}
+
+		_, err = secretClient.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to remove migrated values from global secret")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+// InstallSelection limits a manifest operation to part of the manifest's dependency graph
+type InstallSelection struct {
+	// Only lists the installs or bundles to include. A bundle name includes all the installs created from the bundle,
+	// such as postgres-auth for postgres. Everything is included when empty.
+	Only []string
+
+	// WithDeps also includes the installs the selected installs require, directly or indirectly
+	WithDeps bool
+
+	// WithDependents also includes the installs that require the selected installs, directly or indirectly
+	WithDependents bool
+}
+
+// IsEmpty returns true if the selection includes everything
+func (s InstallSelection) IsEmpty() bool {
+	return len(s.Only) == 0
+}
+
+// selectInstalls returns the installs of a resolved graph included by the selection
+func (s InstallSelection) selectInstalls(graph *installGraph) (map[string]bool, error) {
+	bundles := make(map[string]string, len(graph.installs))
+	for name := range graph.installs {
+		bundles[name] = graph.bundles[name].Name
+	}
+	return s.selectNodes(bundles, graph.dependencies)
+}
+
+// selectBundles returns the bundles of a manifest included by the selection. Bundles aren't installed yet, so their
+// requires are read from the applications in the source, and from the requires listed in the manifest.
+func (s InstallSelection) selectBundles(manifest *v1alpha1.Manifest, source Source, namespace string) (map[string]bool, error) {
+	bundles := make(map[string]string, len(manifest.Spec.Bundles))
+	for _, bundle := range manifest.Spec.Bundles {
+		bundles[bundle.Name] = bundle.Name
+	}
+	if s.IsEmpty() {
+		return s.selectNodes(bundles, nil)
+	}
+
+	apps := make(map[string]*v1alpha1.Application, len(manifest.Spec.Bundles))
+	providers := make(map[string]string)
+	for _, bundle := range manifest.Spec.Bundles {
+		bundleRef := BundleRef{Name: bundle.Name, Version: bundle.Version}
+		bundleFile, err := source.Get(bundleRef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't get bundle '%s'", bundleRef.Filename())
+		}
+		app, err := bundleFile.Application(namespace)
+		_ = bundleFile.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't read application of bundle '%s'", bundle.Name)
+		}
+		apps[bundle.Name] = app
+		for _, provides := range app.Spec.Provides {
+			providers[provides.Name] = bundle.Name
+		}
+	}
+
+	dependencies := make(map[string][]string, len(manifest.Spec.Bundles))
+	for _, bundle := range manifest.Spec.Bundles {
+		for _, require := range apps[bundle.Name].Spec.Requires {
+			if provider, found := providers[require.Name]; found {
+				dependencies[bundle.Name] = appendUnique(dependencies[bundle.Name], provider)
+			}
+		}
+		for _, require := range bundle.Requires {
+			dependencies[bundle.Name] = appendUnique(dependencies[bundle.Name], require.Name)
+		}
+	}
+	return s.selectNodes(bundles, dependencies)
+}
+
+// selectNodes returns the nodes included by the selection. bundles maps each node to the bundle it's created from, and
+// dependencies maps each node to the nodes it requires.
+func (s InstallSelection) selectNodes(bundles map[string]string, dependencies map[string][]string) (map[string]bool, error) {
+	selected := make(map[string]bool, len(bundles))
+	if s.IsEmpty() {
+		for name := range bundles {
+			selected[name] = true
+		}
+		return selected, nil
+	}
+
+	var unknown []string
+	for _, only := range s.Only {
+		found := false
+		for name, bundle := range bundles {
+			if name == only || bundle == only {
+				selected[name] = true
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, only)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown installs or bundles: %s", strings.Join(unknown, ", "))
+	}
+
+	dependents := make(map[string][]string)
+	for name, deps := range dependencies {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	initial := make([]string, 0, len(selected))
+	for name := range selected {
+		initial = append(initial, name)
+	}
+	sort.Strings(initial)
+	if s.WithDeps {
+		addReachable(selected, initial, dependencies, bundles)
+	}
+	if s.WithDependents {
+		addReachable(selected, initial, dependents, bundles)
+	}
+
+	return selected, nil
+}
+
+// addReachable adds the nodes reachable from the starting nodes through edges to selected
+func addReachable(selected map[string]bool, start []string, edges map[string][]string, nodes map[string]string) {
+	visited := make(map[string]bool)
+	queue := append([]string{}, start...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		if _, found := nodes[name]; found {
+			selected[name] = true
+		}
+		queue = append(queue, edges[name]...)
+	}
+}
+
+// filterLayers returns the layers with only the selected installs. Empty layers are kept so that layer numbers match
+// the full graph.
+func filterLayers(layers [][]string, selected map[string]bool) [][]string {
+	filtered := make([][]string, len(layers))
+	for i, layer := range layers {
+		for _, name := range layer {
+			if selected[name] {
+				filtered[i] = append(filtered[i], name)
+			}
+		}
+	}
+	return filtered
+}
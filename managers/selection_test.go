@@ -0,0 +1,106 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/splunk/kube-bundler/api/v1alpha1"
+)
+
+func TestInstallSelection(t *testing.T) {
+	// web requires postgres-auth and redis, auth requires postgres-auth, worker requires web
+	bundles := map[string]string{
+		"web":           "web",
+		"auth":          "auth",
+		"worker":        "worker",
+		"postgres-auth": "postgres",
+		"postgres-web":  "postgres",
+		"redis":         "redis",
+	}
+	dependencies := map[string][]string{
+		"web":    {"postgres-web", "redis"},
+		"auth":   {"postgres-auth"},
+		"worker": {"web"},
+	}
+
+	tests := []struct {
+		selection InstallSelection
+		expected  []string
+	}{
+		{InstallSelection{}, []string{"auth", "postgres-auth", "postgres-web", "redis", "web", "worker"}},
+		{InstallSelection{Only: []string{"web"}}, []string{"web"}},
+		{InstallSelection{Only: []string{"postgres"}}, []string{"postgres-auth", "postgres-web"}},
+		{InstallSelection{Only: []string{"web"}, WithDeps: true}, []string{"postgres-web", "redis", "web"}},
+		{InstallSelection{Only: []string{"web"}, WithDependents: true}, []string{"web", "worker"}},
+		{InstallSelection{Only: []string{"postgres-auth"}, WithDependents: true}, []string{"auth", "postgres-auth"}},
+		{InstallSelection{Only: []string{"redis", "auth"}, WithDeps: true, WithDependents: true}, []string{"auth", "postgres-auth", "redis", "web", "worker"}},
+	}
+	for _, test := range tests {
+		selected, err := test.selection.selectNodes(bundles, dependencies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for name := range selected {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%+v: expected %v, got %v", test.selection, test.expected, names)
+		}
+	}
+
+	_, err := InstallSelection{Only: []string{"web", "nginx"}}.selectNodes(bundles, dependencies)
+	if err == nil || !strings.Contains(err.Error(), "nginx") {
+		t.Errorf("expected unknown install error, got %v", err)
+	}
+}
+
+func TestSelectBundles(t *testing.T) {
+	// web's application requires a database, which postgres provides. The manifest doesn't list the dependency.
+	dir := t.TempDir()
+	writeApp(t, dir, "web", "spec:\n  name: web\n  version: 1.0.0\n  requires:\n    - name: database\n")
+	writeApp(t, dir, "postgres", "spec:\n  name: postgres\n  version: 1.0.0\n  provides:\n    - name: database\n")
+	writeApp(t, dir, "redis", "spec:\n  name: redis\n  version: 1.0.0\n")
+	source, err := NewSource("directory", dir, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &v1alpha1.Manifest{
+		Spec: v1alpha1.ManifestSpec{
+			Bundles: []v1alpha1.BundleSpec{
+				{Name: "web", Version: "1.0.0"},
+				{Name: "postgres", Version: "1.0.0"},
+				{Name: "redis", Version: "1.0.0"},
+			},
+		},
+	}
+
+	selected, err := InstallSelection{Only: []string{"web"}, WithDeps: true}.selectBundles(manifest, source, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"web": true, "postgres": true}
+	if !reflect.DeepEqual(selected, expected) {
+		t.Errorf("expected %v, got %v", expected, selected)
+	}
+}
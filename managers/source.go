@@ -258,6 +258,9 @@ func (ds *DirectorySource) Get(bundleRef BundleRef) (*BundleFile, error) {
 	}
 
 	fullPath := filepath.Join(ds.Path, ds.Section, ds.Release, bundleRef.Filename())
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
 	return NewBundleFromFile(fullPath)
 }
 
@@ -38,12 +38,13 @@ func NewStatusManager(kbClient KBClient) *StatusManager {
 	}
 }
 
-func (sm *StatusManager) HealthStatus() error {
+// HealthStatus serves the health of the applications and installs in namespace, and of the cluster nodes
+func (sm *StatusManager) HealthStatus(namespace string) error {
 	ctx := context.Background()
 
-	go sm.updateAppList(ctx)
+	go sm.updateAppList(ctx, namespace)
 	go sm.updateClusterList(ctx)
-	go sm.updateResourceList(ctx)
+	go sm.updateResourceList(ctx, namespace)
 
 	// prometheus metrics endpoint
 	go func() {
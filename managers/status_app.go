@@ -77,11 +77,11 @@ var appHealthStatusCheckGauge = promauto.NewGaugeVec(
 	}, []string{"app", "endpoint"})
 
 // routinely checks for changes to application list and starts/stops goroutines accordingly
-func (sm *StatusManager) updateAppList(ctx context.Context) {
+func (sm *StatusManager) updateAppList(ctx context.Context, namespace string) {
 	for {
 		updatedMap := make(map[string]struct{})
 		var updatedList v1alpha1.ApplicationList
-		err := sm.resourceMgr.List(ctx, defaultNamespace, &updatedList)
+		err := sm.resourceMgr.List(ctx, namespace, &updatedList)
 		if err != nil {
 			log.WithField("err", err).Warn("couldn't get applications")
 			time.Sleep(30 * time.Second)
@@ -57,16 +57,16 @@ var resourceStatusCheckGauge = promauto.NewGaugeVec(
 		Help: "The status of kubernetes resources for every service.",
 	}, []string{"name", "type", "service", "category"})
 
-func (sm *StatusManager) updateResourceList(ctx context.Context) {
+func (sm *StatusManager) updateResourceList(ctx context.Context, namespace string) {
 	for {
 		var apps v1alpha1.ApplicationList
-		if err := sm.resourceMgr.List(ctx, defaultNamespace, &apps); err != nil {
+		if err := sm.resourceMgr.List(ctx, namespace, &apps); err != nil {
 			log.WithField("err", err).Warn("couldn't get applications")
 			time.Sleep(30 * time.Second)
 			continue
 		}
 		var installs v1alpha1.InstallList
-		if err := sm.resourceMgr.List(ctx, defaultNamespace, &installs); err != nil {
+		if err := sm.resourceMgr.List(ctx, namespace, &installs); err != nil {
 			log.WithField("err", err).Warn("couldn't get installs")
 			time.Sleep(30 * time.Second)
 			continue
@@ -90,7 +90,7 @@ func (sm *StatusManager) updateResourceList(ctx context.Context) {
 				log.WithField("install", i.Name).Debugf("Getting config value 'namespace' from install")
 				installRef := InstallReference{
 					Name:      i.Name,
-					Namespace: defaultNamespace,
+					Namespace: namespace,
 				}
 				ns, err := sm.configMgr.Get(ctx, installRef, "namespace")
 				if err != nil {
@@ -113,7 +113,7 @@ func (sm *StatusManager) updateResourceList(ctx context.Context) {
 				case "job":
 					deployable = resources.NewJob(sm.kbClient, r.Category, i.Name, name, ns)
 				case "kubegres":
-					deployable = resources.NewKubegres(sm.kbClient, r.Category, i.Name, name, ns)
+					deployable = resources.NewKubegres(sm.kbClient, sm.kbClient.RestConfig, r.Category, i.Name, name, ns)
 				case "statefulset":
 					deployable = resources.NewStatefulSet(sm.kbClient, r.Category, i.Name, name, ns)
 				default:
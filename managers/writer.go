@@ -0,0 +1,75 @@
+/*
+   Copyright 2023 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package managers
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// PrefixWriter prefixes each line written to it. Several PrefixWriters may share a mutex so that lines from concurrent
+// deploys are interleaved without being split.
+type PrefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func NewPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *PrefixWriter {
+	return &PrefixWriter{
+		mu:     mu,
+		w:      w,
+		prefix: []byte(prefix),
+	}
+}
+
+// Write buffers p and writes out every complete line
+func (pw *PrefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		err := pw.writeLine(pw.buf[:i+1])
+		if err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes out any remaining partial line
+func (pw *PrefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	err := pw.writeLine(append(pw.buf, '\n'))
+	pw.buf = nil
+	return err
+}
+
+func (pw *PrefixWriter) writeLine(line []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	_, err := pw.w.Write(append(append([]byte{}, pw.prefix...), line...))
+	return err
+}
@@ -27,12 +27,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/rest"
 )
 
 // KubegresResource is a resource for kubernetes Kubegres
 type KubegresResource struct {
 	clientset   kubernetes.Interface
+	restConfig  *rest.Config
 	category    string
 	serviceName string
 	name        string
@@ -43,9 +44,10 @@ type KubegresResource struct {
 	totalReplicas     int
 }
 
-func NewKubegres(clientset kubernetes.Interface, category, serviceName, name, namespace string) DeployableResource {
+func NewKubegres(clientset kubernetes.Interface, restConfig *rest.Config, category, serviceName, name, namespace string) DeployableResource {
 	return &KubegresResource{
 		clientset:   clientset,
+		restConfig:  restConfig,
 		category:    category,
 		serviceName: serviceName,
 		name:        name,
@@ -72,11 +74,10 @@ func (k *KubegresResource) getKubegresStatefulSets() ([]DeployableResource, erro
 }
 
 func (k *KubegresResource) getKubegresClient() (dynamic.ResourceInterface, error) {
-	kubeconfig, err := ctrl.GetConfig()
+	client, err := dynamic.NewForConfig(k.restConfig)
 	if err != nil {
-		return nil, errors.Wrapf(err, "couldn't get kubeconfig")
+		return nil, errors.Wrap(err, "couldn't create dynamic client")
 	}
-	client := dynamic.NewForConfigOrDie(kubeconfig)
 	resource := schema.GroupVersionResource{
 		Group:    "kubegres.reactive-tech.io",
 		Version:  "v1",